@@ -0,0 +1,345 @@
+package imap
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ACL extension (RFC 4314)
+
+// ExtACL advertises ACL (RFC 4314).
+var ExtACL = newExtension(CapAuthenticated, "ACL", "RIGHTS=texk")
+
+type Right byte
+
+const (
+	RightLookup        Right = 'l' // mailbox is visible to LIST
+	RightRead                = 'r' // SELECT, EXAMINE, FETCH, SEARCH, COPY from
+	RightSeen                = 's' // keep \Seen across sessions
+	RightWrite               = 'w' // set flags other than \Seen and \Deleted
+	RightInsert              = 'i' // APPEND, COPY into
+	RightPost                = 'p' // send mail to the submission address
+	RightCreate              = 'k' // CREATE child mailboxes, RENAME into
+	RightDeleteMailbox       = 'x' // DELETE, RENAME from
+	RightDeleteMessage       = 't' // set or clear \Deleted
+	RightExpunge             = 'e' // EXPUNGE
+	RightAdmin               = 'a' // SETACL, DELETEACL, GETACL, LISTRIGHTS
+)
+
+// AllRights is every right defined by RFC 4314, in canonical order.
+const AllRights RightSet = "lrswipkxtea"
+
+// RightSet is a set of rights, written as a string of right characters.
+type RightSet string
+
+// ParseRightSet validates a rights string. The obsolete RFC 2086 rights
+// are mapped to their RFC 4314 equivalents: "c" to "k" and "d" to "xte".
+func ParseRightSet(s string) (RightSet, error) {
+	var rights RightSet
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == 'c':
+			rights = rights.Add("k")
+		case c == 'd':
+			rights = rights.Add("xte")
+		case strings.IndexByte(string(AllRights), c) >= 0:
+			rights = rights.Add(RightSet(s[i : i+1]))
+		case c >= '0' && c <= '9':
+			// RFC 4314 reserves digits for implementation-defined rights
+			rights = rights.Add(RightSet(s[i : i+1]))
+		default:
+			return "", ProtocolErrorf("unknown right %q", c)
+		}
+	}
+	return rights, nil
+}
+
+// Has reports whether the set contains every right in other.
+func (rs RightSet) Has(other RightSet) bool {
+	for i := 0; i < len(other); i++ {
+		if strings.IndexByte(string(rs), other[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (rs RightSet) Add(other RightSet) RightSet {
+	for i := 0; i < len(other); i++ {
+		if strings.IndexByte(string(rs), other[i]) < 0 {
+			rs += other[i : i+1]
+		}
+	}
+	return rs
+}
+
+func (rs RightSet) Remove(other RightSet) RightSet {
+	var out RightSet
+	for i := 0; i < len(rs); i++ {
+		if strings.IndexByte(string(other), rs[i]) < 0 {
+			out += rs[i : i+1]
+		}
+	}
+	return out
+}
+
+// Apply changes the set as a SETACL would: replacing it, or adding or
+// removing rights.
+func (rs RightSet) Apply(op StoreOp, rights RightSet) RightSet {
+	switch op {
+	case StoreAdd:
+		return rs.Add(rights)
+	case StoreRemove:
+		return rs.Remove(rights)
+	}
+	return rights
+}
+
+// ACLEntry grants rights to an identifier, a user or group name.
+type ACLEntry struct {
+	Identifier string
+	Rights     RightSet
+}
+
+// ReadSetACL reads the arguments of `SETACL mailbox identifier [+|-]rights`.
+func (p *Parser) ReadSetACL() (mailbox, identifier string, op StoreOp, rights RightSet) {
+	p.ReadSpace()
+	mailbox = p.ReadMailbox()
+	p.ReadSpace()
+	identifier = p.ReadAstring()
+	p.ReadSpace()
+	s := p.ReadAstring()
+	if !p.Valid() {
+		return "", "", 0, ""
+	}
+
+	if strings.HasPrefix(s, "+") {
+		op, s = StoreAdd, s[1:]
+	} else if strings.HasPrefix(s, "-") {
+		op, s = StoreRemove, s[1:]
+	}
+
+	rights, err := ParseRightSet(s)
+	if err != nil {
+		p.err = err
+		return "", "", 0, ""
+	}
+	return
+}
+
+// ReadDeleteACL reads the arguments of `DELETEACL mailbox identifier`.
+func (p *Parser) ReadDeleteACL() (mailbox, identifier string) {
+	p.ReadSpace()
+	mailbox = p.ReadMailbox()
+	p.ReadSpace()
+	identifier = p.ReadAstring()
+	return
+}
+
+// ReadGetACL reads the argument of GETACL or MYRIGHTS.
+func (p *Parser) ReadGetACL() (mailbox string) {
+	p.ReadSpace()
+	return p.ReadMailbox()
+}
+
+// ReadListRights reads the arguments of `LISTRIGHTS mailbox identifier`.
+func (p *Parser) ReadListRights() (mailbox, identifier string) {
+	return p.ReadDeleteACL()
+}
+
+// WriteACL sends the ACL response to GETACL.
+func (c *Conn) WriteACL(mailbox string, entries []ACLEntry) {
+	s := "ACL " + quoteString(c.encodeMailbox(mailbox))
+	for _, e := range entries {
+		s += fmt.Sprintf(" %s %s", quoteString(e.Identifier), quoteString(string(e.Rights)))
+	}
+	c.Splat(s)
+}
+
+// WriteListRights sends the LISTRIGHTS response: the rights always granted
+// to identifier, followed by groups of rights that can only be granted
+// together.
+func (c *Conn) WriteListRights(mailbox, identifier string, required RightSet, optional []RightSet) {
+	s := fmt.Sprintf("LISTRIGHTS %s %s %s", quoteString(c.encodeMailbox(mailbox)),
+		quoteString(identifier), quoteString(string(required)))
+	for _, rs := range optional {
+		s += " " + quoteString(string(rs))
+	}
+	c.Splat(s)
+}
+
+func (c *Conn) WriteMyRights(mailbox string, rights RightSet) {
+	c.Splat(fmt.Sprintf("MYRIGHTS %s %s", quoteString(c.encodeMailbox(mailbox)), quoteString(string(rights))))
+}
+
+// commandRights are the rights each command needs on the mailbox it names
+// (or, for EXPUNGE and CLOSE, the selected mailbox). CREATE needs them on the parent,
+// COPY and MOVE on the destination.
+var commandRights = map[string]RightSet{
+	"SELECT":     "r",
+	"EXAMINE":    "r",
+	"STATUS":     "r",
+	"EXPUNGE":    "e",
+	"CLOSE":      "e",
+	"APPEND":     "i",
+	"COPY":       "i",
+	"MOVE":       "i",
+	"CREATE":     "k",
+	"DELETE":     "x",
+	"SETACL":     "a",
+	"DELETEACL":  "a",
+	"GETACL":     "a",
+	"LISTRIGHTS": "a",
+}
+
+// CommandRights returns the rights a command requires. STORE depends on
+// the flags being changed; see StoreCommand.RequiredRights.
+func CommandRights(command string) RightSet {
+	return commandRights[strings.ToUpper(command)]
+}
+
+// RequiredRights returns the rights needed to make the change: "s" for
+// \Seen, "t" for \Deleted and "w" for any other flag. Replacing the flag
+// list may clear any flag, so it needs all three.
+func (cmd *StoreCommand) RequiredRights() RightSet {
+	if cmd.Op == StoreReplace {
+		return "stw"
+	}
+
+	var rights RightSet
+	for _, f := range cmd.Flags {
+		switch {
+		case f.Equal(FlagSeen):
+			rights = rights.Add("s")
+		case f.Equal(FlagDeleted):
+			rights = rights.Add("t")
+		default:
+			rights = rights.Add("w")
+		}
+	}
+	return rights
+}
+
+// RightsFunc returns the current user's rights on a mailbox.
+type RightsFunc func(mailbox string) (RightSet, error)
+
+// SetRights enables ACL enforcement for the connection. Without it,
+// Authorize allows everything.
+func (c *Conn) SetRights(f RightsFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rights = f
+}
+
+// Authorize checks that the user holds the needed rights on a mailbox. A
+// user who can neither look up nor read the mailbox is told it doesn't
+// exist, so as not to reveal it (RFC 4314 §6).
+func (c *Conn) Authorize(mailbox string, need RightSet) error {
+	c.mu.Lock()
+	rightsOf := c.rights
+	c.mu.Unlock()
+
+	if rightsOf == nil || need == "" {
+		return nil
+	}
+
+	have, err := rightsOf(mailbox)
+	if err != nil {
+		return err
+	}
+	if have.Has(need) {
+		return nil
+	}
+	if !have.Has("l") && !have.Has("r") {
+		return NewResponseError(CodeNonExistent, "no such mailbox")
+	}
+	return NewResponseError(CodeNoPerm, fmt.Sprintf("permission denied; %q rights required", need))
+}
+
+// authorizeCommand enforces rights before the handler runs: for EXPUNGE on
+// the selected mailbox, and for CREATE and DELETE on the mailbox named
+// (CREATE on its parent, if it has one). Handlers check STORE with
+// AuthorizeStore, and the Conn methods for the other commands check their
+// own rights.
+func (c *Conn) authorizeCommand(r *Request) error {
+	switch r.Command {
+	case "EXPUNGE":
+		if c.State() != StateSelected {
+			return nil
+		}
+		return c.Authorize(c.Selected(), CommandRights(r.Command))
+	case "CREATE", "DELETE":
+		name, ok := peekMailbox(r)
+		if !ok {
+			return nil
+		}
+		if r.Command == "CREATE" {
+			if name, ok = c.parentMailbox(name); !ok {
+				return nil
+			}
+		}
+		return c.Authorize(name, CommandRights(r.Command))
+	}
+	return nil
+}
+
+// peekMailbox reads the mailbox name that starts the command's arguments
+// without consuming it, so that the handler can still read it. A name sent
+// as a literal isn't on the command line yet, and the handler is left to
+// deal with it.
+func peekMailbox(r *Request) (string, bool) {
+	tail := r.Tail()
+	if !strings.HasPrefix(tail, " ") || strings.HasPrefix(tail, " {") {
+		return "", false
+	}
+
+	p := NewParser(&Conn{rwc: lineCloser{strings.NewReader(tail)}})
+	p.utf8Accept = r.utf8Accept
+	p.ReadSpace()
+	name := p.ReadMailbox()
+	return name, p.Valid()
+}
+
+// parentMailbox returns the name of the mailbox one level above name,
+// ignoring a trailing delimiter.
+func (c *Conn) parentMailbox(name string) (string, bool) {
+	ref := c.Namespaces().Resolve(name)
+	if ref == nil || ref.Namespace.Delimiter == 0 {
+		return "", false
+	}
+	d := ref.Namespace.Delimiter
+	name = strings.TrimSuffix(name, string(d))
+	i := strings.LastIndexByte(name, d)
+	if i <= 0 {
+		return "", false
+	}
+	return name[:i], true
+}
+
+// lineCloser lets a parser read a single line that is already in memory.
+type lineCloser struct {
+	io.Reader
+}
+
+func (lineCloser) Write(b []byte) (int, error) { return len(b), nil }
+func (lineCloser) Close() error                { return nil }
+
+// AuthorizeStore checks that the user may make a STORE change to the
+// selected mailbox. Handlers call it once Parser.ReadStore has returned.
+func (c *Conn) AuthorizeStore(cmd *StoreCommand) error {
+	if c.State() != StateSelected {
+		return nil
+	}
+	return c.Authorize(c.Selected(), cmd.RequiredRights())
+}
+
+// ExpungeOnClose reports whether CLOSE should expunge the selected
+// mailbox. Like a mailbox opened with EXAMINE, one the user holds no "e"
+// right on is closed silently without expunging (RFC 4314 §4).
+func (c *Conn) ExpungeOnClose() bool {
+	if c.State() != StateSelected || c.ReadOnly() {
+		return false
+	}
+	return c.Authorize(c.Selected(), CommandRights("CLOSE")) == nil
+}
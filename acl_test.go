@@ -0,0 +1,126 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSetACL(t *testing.T) {
+	mailbox, id, op, rights := NewParser(` INBOX/Drafts Chris +cda`).ReadSetACL()
+
+	assert.Equal(t, "INBOX/Drafts", mailbox)
+	assert.Equal(t, "Chris", id)
+	assert.Equal(t, imap.StoreAdd, op)
+	assert.Equal(t, imap.RightSet("kxtea"), rights)
+
+	p := NewParser(` INBOX Chris lrz`)
+	p.ReadSetACL()
+	assert.Error(t, p.Err())
+}
+
+func TestRightSet(t *testing.T) {
+	rs := imap.RightSet("lrs")
+	assert.True(t, rs.Has("rl"))
+	assert.False(t, rs.Has("w"))
+	assert.Equal(t, imap.RightSet("lrsw"), rs.Apply(imap.StoreAdd, "w"))
+	assert.Equal(t, imap.RightSet("l"), rs.Apply(imap.StoreRemove, "rs"))
+	assert.Equal(t, imap.RightSet("i"), rs.Apply(imap.StoreReplace, "i"))
+}
+
+func TestStoreRequiredRights(t *testing.T) {
+	cmd := NewParser(` 1:3 +FLAGS (\Seen \Deleted $Label1)`).ReadStore()
+	assert.Equal(t, imap.RightSet("stw"), cmd.RequiredRights())
+
+	cmd = NewParser(` 1 -FLAGS \Seen`).ReadStore()
+	assert.Equal(t, imap.RightSet("s"), cmd.RequiredRights())
+}
+
+func TestAuthorize(t *testing.T) {
+	conn, out := NewTestConn("")
+	assert.NoError(t, conn.Authorize("INBOX", "a"))
+
+	conn.SetRights(func(mailbox string) (imap.RightSet, error) {
+		if mailbox == "Shared" {
+			return "lr", nil
+		}
+		return "", nil
+	})
+
+	err := conn.Authorize("Shared", imap.CommandRights("SELECT"))
+	assert.NoError(t, err)
+
+	err = conn.Authorize("Shared", imap.CommandRights("DELETE"))
+	conn.No(&imap.Request{Tag: "a1", Command: "DELETE"}, err)
+	assert.Equal(t, "a1 NO [NOPERM] permission denied; \"x\" rights required\r\n", out.String())
+
+	out.Reset()
+	err = conn.Authorize("Secret", imap.CommandRights("SELECT"))
+	conn.No(&imap.Request{Tag: "a2", Command: "SELECT"}, err)
+	assert.Equal(t, "a2 NO [NONEXISTENT] no such mailbox\r\n", out.String())
+}
+
+func TestExpungeOnClose(t *testing.T) {
+	conn, _ := NewTestConn("")
+	conn.SetState(imap.StateAuthenticated)
+	conn.Select(&imap.Request{Tag: "a1", Command: "SELECT"}, namedMailbox("Shared"), &imap.MailboxStatus{})
+	assert.True(t, conn.ExpungeOnClose())
+
+	conn.SetRights(func(mailbox string) (imap.RightSet, error) {
+		return "lrs", nil
+	})
+	assert.False(t, conn.ExpungeOnClose())
+}
+
+func TestConnMethodsAuthorize(t *testing.T) {
+	conn, _ := NewTestConn("a1 APPEND INBOX {3}\r\nabc")
+	conn.SetState(imap.StateAuthenticated)
+	conn.SetRights(func(mailbox string) (imap.RightSet, error) {
+		switch mailbox {
+		case "Shared":
+			return "lr", nil
+		case "INBOX":
+			return "lrs", nil
+		case "Drafts":
+			return "lri", nil
+		}
+		return "", nil
+	})
+
+	req, _ := conn.ReadRequest()
+	err := conn.Select(req, namedMailbox("Secret"), &imap.MailboxStatus{})
+	if assert.Error(t, err) {
+		assert.Equal(t, "no such mailbox", err.Error())
+	}
+	assert.Equal(t, imap.StateAuthenticated, conn.State())
+
+	assert.NoError(t, conn.Select(&imap.Request{Tag: "a2", Command: "SELECT"}, namedMailbox("INBOX"), &imap.MailboxStatus{}))
+
+	req.Command = "COPY"
+	err = conn.Copy(req, &copyingMailbox{namedMailbox: "INBOX"}, imap.NewSequenceSet([]int{1}), "Shared")
+	if assert.Error(t, err) {
+		assert.Equal(t, `permission denied; "i" rights required`, err.Error())
+	}
+
+	req.Command = "MOVE"
+	err = conn.Move(req, &copyingMailbox{namedMailbox: "INBOX"}, imap.NewSequenceSet([]int{1}), "Drafts")
+	if assert.Error(t, err) {
+		assert.Equal(t, `permission denied; "te" rights required`, err.Error())
+	}
+
+	req.Command = "APPEND"
+	err = conn.Append(req, &quotaMailbox{})
+	if assert.Error(t, err) {
+		assert.Equal(t, `permission denied; "i" rights required`, err.Error())
+	}
+}
+
+func TestWriteACL(t *testing.T) {
+	conn, out := NewTestConn("")
+	conn.WriteACL("INBOX", []imap.ACLEntry{{Identifier: "Fred", Rights: "rwipslxetad"}})
+	conn.WriteMyRights("INBOX", "rwiptsldaex")
+
+	assert.Equal(t, "* ACL \"INBOX\" \"Fred\" \"rwipslxetad\"\r\n"+
+		"* MYRIGHTS \"INBOX\" \"rwiptsldaex\"\r\n", out.String())
+}
@@ -0,0 +1,359 @@
+package imap
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"time"
+)
+
+// APPEND, including the MULTIAPPEND (RFC 3502) and CATENATE (RFC 4469)
+// extensions.
+
+var (
+	ExtMultiAppend = newExtension(CapAuthenticated, "MULTIAPPEND")
+	ExtCatenate    = newExtension(CapAuthenticated, "CATENATE")
+)
+
+var CodeTooBig = ResponseCode{Name: "TOOBIG"}
+
+var errMessageTooBig = NewResponseError(CodeTooBig, "message too large")
+
+func CodeBadURL(url string) ResponseCode {
+	return ResponseCode{Name: "BADURL", Args: []string{url}}
+}
+
+type AppendMessage struct {
+	Flags []Flag
+	Date  time.Time // zero if the client didn't send one
+
+	// Body streams the message literal directly from the connection. It is
+	// nil for CATENATE, where the message is assembled from parts instead.
+	Body io.Reader
+
+	p        *Parser
+	catenate bool
+	done     bool
+	size     int64 // CATENATE octets read so far
+}
+
+// CatenatePart is one part of a CATENATE message: either a URL referring to
+// an existing message or section, or a literal.
+type CatenatePart struct {
+	URL  string
+	Text io.Reader
+}
+
+// SetMaxMessageSize limits the size of messages accepted by APPEND.
+// Larger messages are refused with `NO [TOOBIG]`, before the client sends
+// them if it is waiting for a continuation. Zero means no limit. The limit
+// is also advertised as APPENDLIMIT (RFC 7889).
+func (c *Conn) SetMaxMessageSize(n int) {
+	if old := c.parser.maxMessageSize; old > 0 {
+		c.caps.Unregister("APPENDLIMIT=" + strconv.Itoa(old))
+	}
+	c.parser.maxMessageSize = n
+	if n > 0 {
+		c.caps.Register("APPENDLIMIT="+strconv.Itoa(n), CapAuthenticated)
+	}
+}
+
+// ReadAppendMailbox reads the mailbox name at the start of an APPEND. It is
+// read separately so the server can check the mailbox exists (and reply
+// `NO [TRYCREATE]`) before asking the client for the message.
+func (p *Parser) ReadAppendMailbox() string {
+	p.ReadSpace()
+	return p.ReadMailbox()
+}
+
+// ReadAppendMessage reads the next message of an APPEND. It returns nil
+// once all messages have been read; the caller should then call ReadEOL.
+//
+// Each message's Body must be consumed (or abandoned) before the next call,
+// which discards any unread remainder. With MULTIAPPEND, RFC 3502 requires
+// that either all messages are appended or none are; see
+// MailboxMultiAppender.
+func (p *Parser) ReadAppendMessage() *AppendMessage {
+	if p.utf8Append {
+		p.utf8Append = false
+		p.ReadListEnd()
+	}
+	if !p.Valid() || !p.accept(" ") {
+		return nil
+	}
+
+	msg := &AppendMessage{p: p}
+
+	if p.Peek() == '(' {
+		msg.Flags = p.ReadFlagList()
+		p.ReadSpace()
+	}
+	if p.Peek() == '"' {
+		msg.Date = p.ReadDateTime()
+		p.ReadSpace()
+	}
+	if !p.Valid() {
+		return nil
+	}
+
+	if p.accept("CATENATE (") {
+		p.listDepth++
+		msg.catenate = true
+		return msg
+	}
+
+	// RFC 6855 §4: `UTF8 (~{n}...)`, a message with 8-bit headers
+	if p.accept("UTF8 (") {
+		if !p.utf8Accept {
+			p.err = ProtocolError("UTF8 append requires UTF8=ACCEPT")
+			return nil
+		}
+		p.listDepth++
+		p.Expect("~")
+		p.utf8Append = true
+	}
+
+	msg.Body = p.readMessageLiteral(0)
+	if !p.Valid() {
+		return nil
+	}
+	return msg
+}
+
+// readMessageLiteral reads a message literal, enforcing the maximum message
+// size and quota. used is the size of the message so far, for CATENATE.
+func (p *Parser) readMessageLiteral(used int64) io.Reader {
+	size, sync := p.ReadLiteralPrefix()
+	if !p.Valid() {
+		return nil
+	}
+
+	total := used + int64(size)
+	if p.maxMessageSize > 0 && total > int64(p.maxMessageSize) {
+		p.rejectLiteral(size, sync, errMessageTooBig)
+		return nil
+	}
+	if p.checkQuota != nil {
+		if err := p.checkQuota(total); err != nil {
+			p.rejectLiteral(size, sync, err)
+			return nil
+		}
+	}
+
+	return p.streamLiteral(size, sync)
+}
+
+// IsCatenate reports whether the message is assembled from parts.
+func (m *AppendMessage) IsCatenate() bool {
+	return m.catenate
+}
+
+// NextPart returns the next part of a CATENATE message, or nil after the
+// last one. The previous part's Text must be consumed first. The maximum
+// message size applies to the whole message; when reading parts directly
+// rather than through Reader, URL parts aren't counted.
+func (m *AppendMessage) NextPart() *CatenatePart {
+	p := m.p
+	if !m.catenate || m.done || !p.Valid() {
+		return nil
+	}
+
+	if p.accept(")") {
+		p.listDepth--
+		m.done = true
+		return nil
+	}
+	p.accept(" ")
+
+	part := &CatenatePart{}
+	if p.accept("URL ") {
+		part.URL = p.ReadAstring()
+	} else if p.accept("TEXT ") {
+		part.Text = p.readMessageLiteral(m.size)
+	} else if p.Valid() {
+		p.err = ProtocolErrorf("invalid catenate part near %q", p.Tail())
+	}
+
+	if !p.Valid() {
+		return nil
+	}
+	return part
+}
+
+// Reader streams the whole message. For CATENATE messages, resolve is
+// called to fetch the content of each URL part.
+func (m *AppendMessage) Reader(resolve func(url string) (io.Reader, error)) io.Reader {
+	if !m.catenate {
+		return m.Body
+	}
+	return &catenateReader{msg: m, resolve: resolve}
+}
+
+type catenateReader struct {
+	msg     *AppendMessage
+	resolve func(url string) (io.Reader, error)
+	cur     io.Reader
+}
+
+func (r *catenateReader) Read(b []byte) (int, error) {
+	for {
+		if r.cur != nil {
+			n, err := r.cur.Read(b)
+			r.msg.size += int64(n)
+			if max := r.msg.p.maxMessageSize; max > 0 && r.msg.size > int64(max) {
+				return 0, errMessageTooBig
+			}
+			if err != io.EOF {
+				return n, err
+			}
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+		}
+
+		part := r.msg.NextPart()
+		if part == nil {
+			if err := r.msg.p.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+
+		if part.Text != nil {
+			r.cur = part.Text
+			continue
+		}
+
+		if r.resolve == nil {
+			return 0, NewResponseError(CodeBadURL(part.URL), "URL not supported")
+		}
+		cur, err := r.resolve(part.URL)
+		if err != nil {
+			return 0, err
+		}
+		r.cur = cur
+	}
+}
+
+// MailboxURLResolver is implemented by backends that support URL parts in
+// CATENATE.
+type MailboxURLResolver interface {
+	ResolveURL(url string) (io.Reader, error)
+}
+
+// MailboxMultiAppender is implemented by mailboxes that stage appended
+// messages until the command completes, so that a MULTIAPPEND stores
+// either all of its messages or none (RFC 3502). Conn.Append calls
+// CommitAppend once every message has been read and appended, or
+// AbortAppend if the command fails part-way. Other mailboxes have every
+// message read into memory before the first is appended.
+type MailboxMultiAppender interface {
+	MailboxAppender
+
+	CommitAppend() error
+	AbortAppend()
+}
+
+// Append reads the messages of an APPEND (or MULTIAPPEND), stores them
+// through the backend and completes the request with an APPENDUID response
+// code. The mailbox name must already have been read. The user needs the
+// "i" right on the mailbox.
+func (c *Conn) Append(r *Request, mbox MailboxAppender) error {
+	if err := c.Authorize(mbox.Name(), CommandRights("APPEND")); err != nil {
+		return err
+	}
+	if q, ok := mbox.(MailboxQuota); ok {
+		r.checkQuota = q.CheckQuota
+		defer func() { r.checkQuota = nil }()
+	}
+
+	var resolve func(string) (io.Reader, error)
+	if resolver, ok := mbox.(MailboxURLResolver); ok {
+		resolve = resolver.ResolveURL
+	}
+
+	multi, ok := mbox.(MailboxMultiAppender)
+	if !ok {
+		multi = &appendStage{MailboxAppender: mbox}
+	}
+
+	uids, err := appendMessages(r, multi, resolve)
+	if err != nil {
+		multi.AbortAppend()
+		return err
+	}
+	if err := multi.CommitAppend(); err != nil {
+		return err
+	}
+	if stage, ok := multi.(*appendStage); ok {
+		uids = stage.uids
+	}
+
+	c.OkWithCode(r, CodeAppendUID(mbox.UIDValidity(), NewSequenceSet(uids)))
+	return nil
+}
+
+func appendMessages(r *Request, mbox MailboxAppender, resolve func(string) (io.Reader, error)) ([]int, error) {
+	var uids []int
+	for msg := r.ReadAppendMessage(); msg != nil; msg = r.ReadAppendMessage() {
+		uid, err := mbox.Append(msg.Flags, msg.Date, msg.Reader(resolve))
+		if err != nil {
+			return nil, err
+		}
+		if !r.Valid() {
+			return nil, r.Err()
+		}
+		uids = append(uids, uid)
+	}
+
+	r.ReadEOL()
+	if !r.Valid() {
+		return nil, r.Err()
+	}
+	if len(uids) == 0 {
+		return nil, ProtocolError("APPEND requires a message")
+	}
+	return uids, nil
+}
+
+// appendStage holds the messages of an APPEND in memory for a mailbox that
+// can't stage them itself, so that nothing is appended unless every message
+// has been read.
+type appendStage struct {
+	MailboxAppender
+
+	msgs []stagedMessage
+	uids []int
+}
+
+type stagedMessage struct {
+	flags []Flag
+	date  time.Time
+	body  []byte
+}
+
+func (s *appendStage) Append(flags []Flag, date time.Time, body io.Reader) (int, error) {
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return 0, err
+	}
+	s.msgs = append(s.msgs, stagedMessage{flags: flags, date: date, body: b})
+	return 0, nil
+}
+
+func (s *appendStage) CommitAppend() error {
+	for _, m := range s.msgs {
+		uid, err := s.MailboxAppender.Append(m.flags, m.date, bytes.NewReader(m.body))
+		if err != nil {
+			return err
+		}
+		s.uids = append(s.uids, uid)
+	}
+	return nil
+}
+
+func (s *appendStage) AbortAppend() {
+	s.msgs = nil
+}
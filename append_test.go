@@ -0,0 +1,207 @@
+package imap_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadAppendMultiple(t *testing.T) {
+	conn, out := NewTestConn("A003 APPEND saved (\\Seen) \"7-Feb-1994 21:52:25 -0800\" {5}\r\nHello {6+}\r\nWorld!")
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "saved", req.ReadAppendMailbox())
+
+	msg := req.ReadAppendMessage()
+	if assert.NotNil(t, msg, req.Err()) {
+		body, _ := ioutil.ReadAll(msg.Body)
+		assert.Equal(t, "Hello", string(body))
+		assert.Equal(t, []imap.Flag{imap.FlagSeen}, msg.Flags)
+		assert.Equal(t, time.Date(1994, time.February, 8, 5, 52, 25, 0, time.UTC), msg.Date.UTC())
+	}
+
+	msg = req.ReadAppendMessage()
+	if assert.NotNil(t, msg, req.Err()) {
+		body, _ := ioutil.ReadAll(msg.Body)
+		assert.Equal(t, "World!", string(body))
+	}
+
+	assert.Nil(t, req.ReadAppendMessage())
+	req.ReadEOL()
+	assert.NoError(t, req.Err())
+	assert.Equal(t, "+ ready\r\n", out.String(), "only the synchronizing literal needs a continuation")
+}
+
+func TestReadAppendCatenate(t *testing.T) {
+	conn, _ := NewTestConn("A1 APPEND Drafts CATENATE (URL \"/Drafts;UID=20/;section=1\" TEXT {4+}\r\n--x\n)")
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+
+	msg := req.ReadAppendMessage()
+	if !assert.NotNil(t, msg, req.Err()) {
+		return
+	}
+	assert.True(t, msg.IsCatenate())
+
+	body, err := ioutil.ReadAll(msg.Reader(func(url string) (io.Reader, error) {
+		return strings.NewReader("[" + url + "]"), nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "[/Drafts;UID=20/;section=1]--x\n", string(body))
+
+	assert.Nil(t, req.ReadAppendMessage())
+	req.ReadEOL()
+	assert.NoError(t, req.Err())
+}
+
+func TestReadAppendTooBig(t *testing.T) {
+	conn, out := NewTestConn("A1 APPEND INBOX {5000}")
+	conn.SetMaxMessageSize(4096)
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+
+	assert.Nil(t, req.ReadAppendMessage())
+	conn.No(req, req.Err())
+	assert.Equal(t, "A1 NO [TOOBIG] message too large\r\n", out.String())
+}
+
+// stagingMailbox implements MailboxMultiAppender, failing any message
+// whose body starts with "fail".
+type stagingMailbox struct {
+	staged    []string
+	committed []string
+	aborted   bool
+}
+
+func (m *stagingMailbox) Name() string     { return "INBOX" }
+func (m *stagingMailbox) UIDValidity() int { return 7 }
+
+func (m *stagingMailbox) Append(flags []imap.Flag, date time.Time, body io.Reader) (int, error) {
+	buf := make([]byte, 4)
+	n, _ := io.ReadFull(body, buf)
+	if string(buf[:n]) == "fail" {
+		return 0, errors.New("backend failure")
+	}
+	rest, err := ioutil.ReadAll(body)
+	if err != nil {
+		return 0, err
+	}
+	m.staged = append(m.staged, string(buf[:n])+string(rest))
+	return len(m.staged), nil
+}
+
+func (m *stagingMailbox) CommitAppend() error {
+	m.committed, m.staged = m.staged, nil
+	return nil
+}
+
+func (m *stagingMailbox) AbortAppend() {
+	m.staged, m.aborted = nil, true
+}
+
+func TestMultiAppendCommits(t *testing.T) {
+	conn, out := NewTestConn("A1 APPEND INBOX {5+}\r\nHello {5+}\r\nWorld")
+	mbox := &stagingMailbox{}
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+
+	assert.NoError(t, conn.Append(req, mbox))
+	assert.Equal(t, []string{"Hello", "World"}, mbox.committed)
+	assert.Equal(t, "A1 OK [APPENDUID 7 1:2] APPEND completed\r\n", out.String())
+}
+
+func TestMultiAppendAbortsAndResyncs(t *testing.T) {
+	conn, out := NewTestConn("A1 APPEND INBOX {5}\r\nHello {8}\r\nfailure! (\\Seen) {3+}\r\nabc\r\nA2 NOOP")
+	mbox := &stagingMailbox{}
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+
+	err := conn.Append(req, mbox)
+	conn.No(req, err)
+	assert.True(t, mbox.aborted)
+	assert.Nil(t, mbox.committed)
+	assert.Equal(t, "+ ready\r\n+ ready\r\nA1 NO backend failure\r\n", out.String())
+
+	expectNext(t, conn, "A2")
+}
+
+func TestAppendTooBigResyncs(t *testing.T) {
+	conn, _ := NewTestConn("A1 APPEND INBOX {5000+}\r\n" + strings.Repeat("x", 5000) + "\r\nA2 NOOP")
+	conn.SetMaxMessageSize(4096)
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+
+	err := conn.Append(req, &stagingMailbox{})
+	assert.Equal(t, imap.CodeTooBig, err.(*imap.ResponseError).Code)
+
+	expectNext(t, conn, "A2")
+}
+
+func TestCatenateTotalSize(t *testing.T) {
+	conn, _ := NewTestConn("A1 APPEND Drafts CATENATE (TEXT {6+}\r\nabcdef TEXT {6+}\r\nghijkl)\r\nA2 NOOP")
+	conn.SetMaxMessageSize(10)
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+
+	err := conn.Append(req, &stagingMailbox{})
+	if assert.Error(t, err) {
+		assert.Equal(t, imap.CodeTooBig, err.(*imap.ResponseError).Code)
+	}
+
+	expectNext(t, conn, "A2")
+}
+
+func TestSetMaxMessageSizeReplacesLimit(t *testing.T) {
+	conn, _ := NewTestConn("")
+	conn.SetMaxMessageSize(100)
+	conn.SetMaxMessageSize(200)
+	assert.Equal(t, []string{"IMAP4rev1", "LITERAL+", "APPENDLIMIT=200"}, conn.Capabilities().List(true, false))
+
+	conn.SetMaxMessageSize(0)
+	assert.Equal(t, []string{"IMAP4rev1", "LITERAL+"}, conn.Capabilities().List(true, false))
+}
+
+// plainMailbox appends each message as soon as it's given one.
+type plainMailbox struct {
+	bodies []string
+}
+
+func (m *plainMailbox) Name() string     { return "INBOX" }
+func (m *plainMailbox) UIDValidity() int { return 7 }
+
+func (m *plainMailbox) Append(flags []imap.Flag, date time.Time, body io.Reader) (int, error) {
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return 0, err
+	}
+	m.bodies = append(m.bodies, string(b))
+	return 100 + len(m.bodies), nil
+}
+
+func TestMultiAppendWithoutStaging(t *testing.T) {
+	conn, out := NewTestConn("A1 APPEND INBOX {3+}\r\nabc oops\r\n" +
+		"A2 APPEND INBOX {3+}\r\nabc {3+}\r\ndef")
+
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+	mbox := &plainMailbox{}
+	assert.Error(t, conn.Append(req, mbox))
+	assert.Len(t, mbox.bodies, 0)
+
+	conn.DiscardLine()
+	out.Reset()
+	req, _ = conn.ReadRequest()
+	req.ReadAppendMailbox()
+	assert.NoError(t, conn.Append(req, mbox))
+	assert.Equal(t, []string{"abc", "def"}, mbox.bodies)
+	assert.Equal(t, "A2 OK [APPENDUID 7 101:102] APPEND completed\r\n", out.String())
+}
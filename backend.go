@@ -0,0 +1,77 @@
+package imap
+
+import (
+	"io"
+	"time"
+)
+
+// go-imap doesn't implement IMAP commands itself, but the response helpers
+// for some commands need to call into the server's storage layer. The
+// interfaces below describe what they expect. Backends implement whichever
+// ones they support.
+
+type Mailbox interface {
+	Name() string
+	UIDValidity() int
+}
+
+// MailboxLocator looks up mailboxes by name. Names are resolved against the
+// connection's namespaces first, so a backend serving shared or other
+// users' mailboxes receives the namespace and owner separately.
+type MailboxLocator interface {
+	Mailbox(ref *MailboxRef) (Mailbox, error)
+}
+
+// MailboxFlagger is implemented by mailboxes that declare their own FLAGS
+// and PERMANENTFLAGS. Others get DefaultFlags and DefaultPermanentFlags.
+type MailboxFlagger interface {
+	Mailbox
+
+	Flags() []Flag
+	PermanentFlags() []Flag
+}
+
+type MailboxAppender interface {
+	Mailbox
+
+	// Append stores a message and returns the UID assigned to it.
+	Append(flags []Flag, date time.Time, body io.Reader) (uid int, err error)
+}
+
+type MailboxCopier interface {
+	Mailbox
+
+	// Copy copies messages to the named mailbox. If uid is set, seqs holds
+	// UIDs rather than sequence numbers.
+	Copy(seqs *SequenceSet, uid bool, dest string) (*CopyResult, error)
+}
+
+// MailboxStorer is implemented by mailboxes that can change message flags.
+type MailboxStorer interface {
+	Mailbox
+
+	// Store applies cmd to messages. If uid is set, seqs holds UIDs rather
+	// than sequence numbers.
+	Store(seqs *SequenceSet, uid bool, cmd *StoreCommand) error
+}
+
+type MailboxExpunger interface {
+	Mailbox
+
+	// Expunge removes messages flagged \Deleted and returns their sequence
+	// numbers, in the order they should be reported. If uids is non-nil,
+	// only messages in it are removed (UID EXPUNGE).
+	Expunge(uids *SequenceSet) ([]int, error)
+}
+
+// CopyResult describes the messages created by a COPY or MOVE. Source and
+// Dest list UIDs in corresponding order.
+type CopyResult struct {
+	UIDValidity int // of the destination mailbox
+	Source      *SequenceSet
+	Dest        *SequenceSet
+}
+
+func (r *CopyResult) ResponseCode() ResponseCode {
+	return CodeCopyUID(r.UIDValidity, r.Source, r.Dest)
+}
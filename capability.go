@@ -0,0 +1,197 @@
+package imap
+
+import (
+	"strings"
+	"sync"
+)
+
+// CapabilityScope restricts when a capability is advertised. Scopes can be
+// combined, e.g. CapNotAuthenticated|CapPlaintext for STARTTLS.
+type CapabilityScope int
+
+const (
+	CapAlways           CapabilityScope = 0
+	CapNotAuthenticated CapabilityScope = 1 << iota // only before login
+	CapAuthenticated                                // only after login
+	CapTLS                                          // only over TLS
+	CapPlaintext                                    // only without TLS
+)
+
+func (s CapabilityScope) matches(authenticated, tls bool) bool {
+	if s&CapNotAuthenticated != 0 && authenticated {
+		return false
+	}
+	if s&CapAuthenticated != 0 && !authenticated {
+		return false
+	}
+	if s&CapTLS != 0 && !tls {
+		return false
+	}
+	if s&CapPlaintext != 0 && tls {
+		return false
+	}
+	return true
+}
+
+type capability struct {
+	name  string
+	scope CapabilityScope
+}
+
+// CapabilityRegistry is the set of capabilities a server supports. IMAP4rev1
+// is always advertised first; everything else is listed in registration
+// order.
+type CapabilityRegistry struct {
+	mu   sync.RWMutex
+	caps []capability
+}
+
+func NewCapabilityRegistry() *CapabilityRegistry {
+	return &CapabilityRegistry{}
+}
+
+// DefaultCapabilities holds the capabilities advertised by every connection.
+// It only contains what the parser always supports (IMAP4rev1, LITERAL+);
+// servers enable the extensions they implement, here or per Conn. Each Conn
+// starts with a copy of it.
+var DefaultCapabilities = NewCapabilityRegistry()
+
+func RegisterCapability(name string, scope CapabilityScope) {
+	DefaultCapabilities.Register(name, scope)
+}
+
+// Register adds a capability, or replaces the scope of an existing one.
+func (r *CapabilityRegistry) Register(name string, scope CapabilityScope) {
+	name = strings.ToUpper(name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, c := range r.caps {
+		if c.name == name {
+			r.caps[i].scope = scope
+			return
+		}
+	}
+	r.caps = append(r.caps, capability{name, scope})
+}
+
+func (r *CapabilityRegistry) Unregister(name string) {
+	name = strings.ToUpper(name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, c := range r.caps {
+		if c.name == name {
+			r.caps = append(r.caps[:i], r.caps[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *CapabilityRegistry) Clone() *CapabilityRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	caps := make([]capability, len(r.caps))
+	copy(caps, r.caps)
+	return &CapabilityRegistry{caps: caps}
+}
+
+// List returns the capabilities advertised in the given state.
+func (r *CapabilityRegistry) List(authenticated, tls bool) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []string{"IMAP4rev1"}
+	for _, c := range r.caps {
+		if c.name != "IMAP4REV1" && c.scope.matches(authenticated, tls) {
+			list = append(list, c.name)
+		}
+	}
+	return list
+}
+
+// Has reports whether a capability is advertised in the given state.
+func (r *CapabilityRegistry) Has(name string, authenticated, tls bool) bool {
+	name = strings.ToUpper(name)
+	for _, c := range r.List(authenticated, tls) {
+		if strings.ToUpper(c) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Extension is the set of capabilities advertised for an extension the
+// library implements. Enable it once the server handles its commands:
+//
+//	imap.DefaultCapabilities.Enable(imap.ExtMove, imap.ExtUIDPlus)
+type Extension struct {
+	caps []capability
+}
+
+func newExtension(scope CapabilityScope, names ...string) *Extension {
+	ext := &Extension{}
+	for _, name := range names {
+		ext.caps = append(ext.caps, capability{name, scope})
+	}
+	return ext
+}
+
+// Enable registers the capabilities of each extension.
+func (r *CapabilityRegistry) Enable(exts ...*Extension) {
+	for _, ext := range exts {
+		for _, c := range ext.caps {
+			r.Register(c.name, c.scope)
+		}
+	}
+}
+
+func init() {
+	RegisterCapability("LITERAL+", CapAlways)
+}
+
+func (c *Conn) Capabilities() *CapabilityRegistry {
+	return c.caps
+}
+
+func (c *Conn) capabilityList() []string {
+	return c.caps.List(c.State() != StateNotAuthenticated, c.IsTLS())
+}
+
+// HasCapability reports whether the capability is currently advertised.
+func (c *Conn) HasCapability(name string) bool {
+	return c.caps.Has(name, c.State() != StateNotAuthenticated, c.IsTLS())
+}
+
+// CapabilityCode returns a CAPABILITY response code for the current state,
+// e.g. `CAPABILITY IMAP4rev1 LITERAL+`.
+func (c *Conn) CapabilityCode() ResponseCode {
+	return CodeCapability(c.capabilityList()...)
+}
+
+// WriteCapability sends the untagged CAPABILITY response.
+func (c *Conn) WriteCapability() {
+	c.Splat(c.CapabilityCode().String())
+}
+
+// Greet sends the initial server greeting, advertising capabilities.
+func (c *Conn) Greet(msg string) {
+	c.SplatOk(c.CapabilityCode(), msg)
+}
+
+// Preauth greets a client that is already authenticated by external means
+// (e.g. a trusted local socket) and moves the connection to the
+// authenticated state.
+func (c *Conn) Preauth(msg string) {
+	c.SetState(StateAuthenticated)
+	c.Splat("PREAUTH [" + c.CapabilityCode().String() + "] " + msg)
+}
+
+// OkWithCapability completes a command that changed the advertised
+// capabilities (LOGIN, AUTHENTICATE, STARTTLS).
+func (c *Conn) OkWithCapability(r *Request) {
+	c.OkWithCode(r, c.CapabilityCode())
+}
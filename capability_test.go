@@ -0,0 +1,102 @@
+package imap_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a transport that records what the server writes.
+type recorder struct {
+	bytes.Buffer
+}
+
+func (r *recorder) Close() error { return nil }
+
+func TestCapabilityScopes(t *testing.T) {
+	r := imap.NewCapabilityRegistry()
+	r.Register("ALWAYS", imap.CapAlways)
+	r.Register("STARTTLS", imap.CapNotAuthenticated|imap.CapPlaintext)
+	r.Register("AUTH=PLAIN", imap.CapNotAuthenticated|imap.CapTLS)
+	r.Register("MOVE", imap.CapAuthenticated)
+
+	tests := []struct {
+		authenticated, tls bool
+		want               []string
+	}{
+		{false, false, []string{"IMAP4rev1", "ALWAYS", "STARTTLS"}},
+		{false, true, []string{"IMAP4rev1", "ALWAYS", "AUTH=PLAIN"}},
+		{true, false, []string{"IMAP4rev1", "ALWAYS", "MOVE"}},
+		{true, true, []string{"IMAP4rev1", "ALWAYS", "MOVE"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, r.List(test.authenticated, test.tls), test)
+	}
+
+	assert.True(t, r.Has("starttls", false, false))
+	assert.False(t, r.Has("STARTTLS", false, true))
+}
+
+func TestCapabilityRegisterReplacesScope(t *testing.T) {
+	r := imap.NewCapabilityRegistry()
+	r.Register("idle", imap.CapAuthenticated)
+	r.Register("IDLE", imap.CapAlways)
+	assert.Equal(t, []string{"IMAP4rev1", "IDLE"}, r.List(false, false))
+}
+
+func TestCapabilityUnregister(t *testing.T) {
+	r := imap.NewCapabilityRegistry()
+	r.Register("A", imap.CapAlways)
+	r.Register("B", imap.CapAlways)
+	r.Unregister("a")
+	r.Unregister("MISSING")
+	assert.Equal(t, []string{"IMAP4rev1", "B"}, r.List(false, false))
+}
+
+func TestCapabilityCloneIsolation(t *testing.T) {
+	r := imap.NewCapabilityRegistry()
+	r.Register("A", imap.CapAlways)
+	clone := r.Clone()
+	clone.Register("B", imap.CapAlways)
+	clone.Unregister("A")
+	r.Register("C", imap.CapAlways)
+
+	assert.Equal(t, []string{"IMAP4rev1", "A", "C"}, r.List(false, false))
+	assert.Equal(t, []string{"IMAP4rev1", "B"}, clone.List(false, false))
+}
+
+func TestDefaultCapabilities(t *testing.T) {
+	assert.Equal(t, []string{"IMAP4rev1", "LITERAL+"}, imap.DefaultCapabilities.List(true, false))
+
+	conn := imap.NewConn(&recorder{})
+	conn.Capabilities().Register("MOVE", imap.CapAuthenticated)
+	assert.False(t, imap.DefaultCapabilities.Has("MOVE", true, false), "registering on a Conn leaves the defaults alone")
+}
+
+func TestGreetCapabilities(t *testing.T) {
+	out := &recorder{}
+	conn := imap.NewConn(out)
+	conn.Capabilities().Register("STARTTLS", imap.CapNotAuthenticated|imap.CapPlaintext)
+	conn.Capabilities().Register("MOVE", imap.CapAuthenticated)
+
+	conn.Greet("ready")
+	assert.Equal(t, "* OK [CAPABILITY IMAP4rev1 LITERAL+ STARTTLS] ready\r\n", out.String())
+	out.Reset()
+
+	conn.SetState(imap.StateAuthenticated)
+	conn.OkWithCapability(&imap.Request{Tag: "a1", Command: "LOGIN"})
+	assert.Equal(t, "a1 OK [CAPABILITY IMAP4rev1 LITERAL+ MOVE] LOGIN completed\r\n", out.String())
+}
+
+func TestPreauthCapabilities(t *testing.T) {
+	out := &recorder{}
+	conn := imap.NewConn(out)
+	conn.Capabilities().Register("STARTTLS", imap.CapNotAuthenticated|imap.CapPlaintext)
+	conn.Capabilities().Register("MOVE", imap.CapAuthenticated)
+
+	conn.Preauth("welcome")
+	assert.Equal(t, "* PREAUTH [CAPABILITY IMAP4rev1 LITERAL+ MOVE] welcome\r\n", out.String())
+	assert.Equal(t, imap.StateAuthenticated, conn.State())
+}
@@ -0,0 +1,97 @@
+package imap
+
+import (
+	"bufio"
+	"compress/flate"
+	"io"
+	"strings"
+)
+
+// COMPRESS extension (RFC 4978)
+
+var ExtCompress = newExtension(CapAuthenticated, "COMPRESS=DEFLATE")
+
+var CodeCompressionActive = ResponseCode{Name: "COMPRESSIONACTIVE"}
+
+// ReadCompress reads the argument of `COMPRESS mechanism`. DEFLATE is the
+// only mechanism defined.
+func (p *Parser) ReadCompress() (mechanism string) {
+	p.ReadSpace()
+	mechanism = strings.ToUpper(p.ReadAtom())
+	if p.Valid() && mechanism != "DEFLATE" {
+		p.err = ProtocolErrorf("unknown compression mechanism %q", mechanism)
+		return ""
+	}
+	return mechanism
+}
+
+// deflateConn compresses a transport with raw DEFLATE (RFC 1951) in both
+// directions.
+type deflateConn struct {
+	rwc io.ReadWriteCloser
+	r   io.ReadCloser
+	w   *flate.Writer
+}
+
+// newDeflateConn wraps rwc. Compressed input is read from br, which may
+// already hold some of it.
+func newDeflateConn(rwc io.ReadWriteCloser, br *bufio.Reader) (*deflateConn, error) {
+	w, err := flate.NewWriter(rwc, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	return &deflateConn{rwc: rwc, r: flate.NewReader(br), w: w}, nil
+}
+
+func (d *deflateConn) Read(b []byte) (int, error) {
+	return d.r.Read(b)
+}
+
+// Write flushes after every write, so responses aren't held back waiting
+// for more output.
+func (d *deflateConn) Write(b []byte) (int, error) {
+	n, err := d.w.Write(b)
+	if err == nil {
+		err = d.w.Flush()
+	}
+	return n, err
+}
+
+func (d *deflateConn) Close() error {
+	d.w.Close()
+	d.r.Close()
+	return d.rwc.Close()
+}
+
+// Compressed reports whether COMPRESS is active.
+func (c *Conn) Compressed() bool {
+	_, ok := c.rwc.(*deflateConn)
+	return ok
+}
+
+// Compress answers a COMPRESS command. After the tagged OK, everything
+// sent and received on the connection is compressed. The command line must
+// have been read completely.
+func (c *Conn) Compress(r *Request) error {
+	if !r.Valid() {
+		return r.Err()
+	}
+	if c.Compressed() {
+		return NewResponseError(CodeCompressionActive, "DEFLATE active via COMPRESS")
+	}
+
+	d, err := newDeflateConn(c.rwc, c.parser.r)
+	if err != nil {
+		return err
+	}
+
+	c.Ok(r)
+
+	// As with STARTTLS, the parser needs a fresh reader. The old one stays
+	// underneath, since it may have buffered the start of the compressed
+	// stream. Only the goroutine serving the connection touches these;
+	// Server.Shutdown closes the network connection directly.
+	c.rwc = d
+	c.parser.r = bufio.NewReaderSize(d, maxTokenSize)
+	return nil
+}
@@ -0,0 +1,47 @@
+package imap_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompress(t *testing.T) {
+	var compressed bytes.Buffer
+	w, _ := flate.NewWriter(&compressed, flate.DefaultCompression)
+	w.Write([]byte("a2 NOOP\r\n"))
+	w.Close()
+
+	b := &splitBuffer{in: bytes.NewBufferString("a1 COMPRESS DEFLATE\r\n")}
+	b.in.Write(compressed.Bytes())
+	conn := imap.NewConn(b)
+
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DEFLATE", req.ReadCompress())
+	req.ReadEOL()
+	assert.NoError(t, conn.Compress(req))
+	assert.Equal(t, "a1 OK COMPRESS completed\r\n", b.out.String())
+	assert.True(t, conn.Compressed())
+
+	req, err = conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "NOOP", req.Command)
+	req.ReadEOL()
+
+	err = conn.Compress(req)
+	assert.Error(t, err)
+
+	b.out.Reset()
+	conn.Ok(req)
+	out, _ := ioutil.ReadAll(flate.NewReader(&b.out))
+	assert.Equal(t, "a2 OK NOOP completed\r\n", string(out))
+}
@@ -0,0 +1,228 @@
+package imap
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// CONDSTORE and QRESYNC extensions (RFC 7162)
+
+// QRESYNC implies CONDSTORE and requires ENABLE (RFC 7162 §3.2.3).
+var (
+	ExtCondStore = newExtension(CapAuthenticated, "CONDSTORE")
+	ExtQResync   = newExtension(CapAuthenticated, "CONDSTORE", "ENABLE", "QRESYNC")
+)
+
+func CodeHighestModSeq(modSeq uint64) ResponseCode {
+	return ResponseCode{
+		Name: "HIGHESTMODSEQ",
+		Args: []string{strconv.FormatUint(modSeq, 10)},
+	}
+}
+
+// CodeModified lists the messages a conditional STORE did not update.
+func CodeModified(set *SequenceSet) ResponseCode {
+	return ResponseCode{Name: "MODIFIED", Args: []string{set.String()}}
+}
+
+var (
+	CodeNoModSeq = ResponseCode{Name: "NOMODSEQ"}
+	CodeClosed   = ResponseCode{Name: "CLOSED"}
+)
+
+var ModSeqFetchAttribute = BasicFetchAttribute{"MODSEQ", MarshalModSeq}
+
+func MarshalModSeq(m *Message) string {
+	return fmt.Sprintf("(%d)", m.ModSeq)
+}
+
+// ReadModSeq reads a mod-sequence-value (a 63-bit unsigned integer).
+func (p *Parser) ReadModSeq() uint64 {
+	data, n := p.Tail(), 0
+	for {
+		p.ensure(n + 1)
+		if !p.Valid() {
+			return 0
+		}
+
+		data = p.Tail()
+		if c := data[n]; c < '0' || c > '9' {
+			break
+		}
+		n++
+	}
+
+	if n < 1 {
+		p.err = InvalidTokenError("mod-sequence", 0, data)
+		return 0
+	}
+
+	modSeq, err := strconv.ParseUint(data[:n], 10, 63)
+	if err != nil {
+		p.err = ProtocolErrorf("invalid mod-sequence %q", data[:n])
+		return 0
+	}
+
+	p.advance(n)
+	return modSeq
+}
+
+type FetchModifiers struct {
+	// ChangedSince restricts the FETCH to messages whose mod-sequence is
+	// greater than this value. Zero means no restriction.
+	ChangedSince uint64
+
+	// Vanished requests VANISHED responses for expunged messages (UID FETCH
+	// only, QRESYNC).
+	Vanished bool
+}
+
+// Matches reports whether a message passes the CHANGEDSINCE filter.
+func (m FetchModifiers) Matches(msg *Message) bool {
+	return m.ChangedSince == 0 || msg.ModSeq > m.ChangedSince
+}
+
+// ReadFetchModifiers reads the optional modifier list that may follow the
+// FETCH attributes, e.g. ` (CHANGEDSINCE 12345 VANISHED)`. VANISHED needs
+// QRESYNC to be enabled and is only valid in UID FETCH.
+func (p *Parser) ReadFetchModifiers() (mods FetchModifiers) {
+	if !p.accept(" (") {
+		return
+	}
+	p.listDepth++
+
+	for {
+		if p.accept("CHANGEDSINCE ") {
+			mods.ChangedSince = p.ReadModSeq()
+			if p.Valid() && mods.ChangedSince == 0 {
+				p.err = ProtocolError("CHANGEDSINCE must be greater than zero")
+			}
+		} else if p.accept("VANISHED") {
+			mods.Vanished = true
+			if !p.qresync {
+				p.err = ProtocolError("QRESYNC must be enabled first")
+			} else if !p.uid {
+				p.err = ProtocolError("VANISHED is only allowed in UID FETCH")
+			}
+		} else if p.Valid() {
+			p.err = ProtocolErrorf("unknown fetch modifier near %q", p.Tail())
+		}
+
+		if !p.Valid() || !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+	if p.Valid() && mods.Vanished && mods.ChangedSince == 0 {
+		p.err = ProtocolError("VANISHED requires CHANGEDSINCE")
+	}
+	return
+}
+
+// ReadStoreModifiers reads the optional ` (UNCHANGEDSINCE n)` that may follow
+// the sequence set of a STORE.
+func (p *Parser) ReadStoreModifiers() (unchangedSince uint64, ok bool) {
+	if !p.accept(" (") {
+		return 0, false
+	}
+	p.listDepth++
+
+	p.Expect("UNCHANGEDSINCE ")
+	unchangedSince = p.ReadModSeq()
+	p.ReadListEnd()
+	return unchangedSince, p.Valid()
+}
+
+type QResyncParams struct {
+	UIDValidity int
+	ModSeq      uint64
+
+	// Optional: the UIDs the client knows about, and a sample of sequence
+	// numbers with their corresponding UIDs.
+	KnownUIDs         *SequenceSet
+	KnownSequenceSet  *SequenceSet
+	KnownSequenceUIDs *SequenceSet
+}
+
+type SelectParams struct {
+	CondStore bool
+	QResync   *QResyncParams
+}
+
+// ReadSelect reads the arguments of SELECT or EXAMINE, including the
+// optional CONDSTORE and QRESYNC parameters. QRESYNC is a protocol error
+// unless the client has enabled it (RFC 7162 §3.2.5). The CONDSTORE
+// parameter enables CONDSTORE once Conn.Select completes (RFC 7162 §3.1.8).
+func (p *Parser) ReadSelect() (mailbox string, params SelectParams) {
+	p.ReadSpace()
+	mailbox = p.ReadMailbox()
+	if !p.Valid() || !p.accept(" (") {
+		return
+	}
+	p.listDepth++
+
+	for {
+		if p.accept("CONDSTORE") {
+			params.CondStore = true
+			p.condStore = true
+		} else if p.accept("QRESYNC ") {
+			if !p.qresync {
+				p.err = ProtocolError("QRESYNC must be enabled first")
+				return
+			}
+			params.QResync = p.readQResyncParams()
+		} else if p.Valid() {
+			p.err = ProtocolErrorf("unknown select parameter near %q", p.Tail())
+		}
+
+		if !p.Valid() || !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+	return
+}
+
+func (p *Parser) readQResyncParams() *QResyncParams {
+	qr := &QResyncParams{}
+
+	p.ReadListStart()
+	qr.UIDValidity = p.ReadInt()
+	p.ReadSpace()
+	qr.ModSeq = p.ReadModSeq()
+
+	if p.accept(" ") {
+		if p.Peek() != '(' {
+			qr.KnownUIDs = p.ReadSequenceSet()
+			if !p.accept(" ") {
+				p.ReadListEnd()
+				return qr
+			}
+		}
+
+		p.ReadListStart()
+		qr.KnownSequenceSet = p.ReadSequenceSet()
+		p.ReadSpace()
+		qr.KnownSequenceUIDs = p.ReadSequenceSet()
+		p.ReadListEnd()
+	}
+
+	p.ReadListEnd()
+	if !p.Valid() {
+		return nil
+	}
+	return qr
+}
+
+// WriteVanished reports expunged UIDs. Earlier is set when responding to a
+// QRESYNC SELECT or a UID FETCH with VANISHED, rather than announcing a new
+// expunge.
+func (c *Conn) WriteVanished(uids *SequenceSet, earlier bool) {
+	if earlier {
+		c.Splat("VANISHED (EARLIER) " + uids.String())
+	} else {
+		c.Splat("VANISHED " + uids.String())
+	}
+}
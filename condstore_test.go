@@ -0,0 +1,148 @@
+package imap_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFetchModifiers(t *testing.T) {
+	p := NewParser(`(FLAGS MODSEQ) (CHANGEDSINCE 12345)`)
+	attrs := p.ReadFetchAttributes()
+	mods := p.ReadFetchModifiers()
+	p.ReadEOL()
+
+	assert.NoError(t, p.Err())
+	if assert.Len(t, attrs, 2) {
+		assert.Equal(t, "FLAGS", attrs[0].Name())
+		assert.Equal(t, "MODSEQ", attrs[1].Name())
+	}
+	assert.Equal(t, imap.FetchModifiers{ChangedSince: 12345}, mods)
+}
+
+func TestReadFetchModifiersVanished(t *testing.T) {
+	conn, _ := NewTestConn("t1 ENABLE QRESYNC\r\n" +
+		"t2 UID FETCH 1:* (FLAGS) (CHANGEDSINCE 12345 VANISHED)")
+	conn.SetState(imap.StateAuthenticated)
+	conn.Capabilities().Enable(imap.ExtQResync)
+
+	req, _ := conn.ReadRequest()
+	caps := req.ReadEnable()
+	req.ReadEOL()
+	conn.Enable(req, caps)
+
+	conn.SetState(imap.StateSelected)
+	req, _ = conn.ReadRequest()
+	req.ReadSpace()
+	req.ReadSequenceSet()
+	req.ReadSpace()
+	req.ReadFetchAttributes()
+	mods := req.ReadFetchModifiers()
+	req.ReadEOL()
+
+	assert.NoError(t, req.Err())
+	assert.Equal(t, imap.FetchModifiers{ChangedSince: 12345, Vanished: true}, mods)
+}
+
+func TestReadFetchModifiersVanishedRejected(t *testing.T) {
+	for _, tc := range []struct {
+		input, want string
+	}{
+		{"t1 UID FETCH 1:* (FLAGS) (CHANGEDSINCE 5 VANISHED)", "QRESYNC must be enabled first"},
+		{"t0 ENABLE QRESYNC\r\nt1 FETCH 1:* (FLAGS) (CHANGEDSINCE 5 VANISHED)", "VANISHED is only allowed in UID FETCH"},
+	} {
+		conn, out := NewTestConn(tc.input)
+		conn.SetState(imap.StateAuthenticated)
+		conn.Capabilities().Enable(imap.ExtQResync)
+
+		req, _ := conn.ReadRequest()
+		if req.Command == "ENABLE" {
+			caps := req.ReadEnable()
+			req.ReadEOL()
+			conn.Enable(req, caps)
+			out.Reset()
+			req, _ = conn.ReadRequest()
+		}
+		conn.SetState(imap.StateSelected)
+		req.ReadSpace()
+		req.ReadSequenceSet()
+		req.ReadSpace()
+		req.ReadFetchAttributes()
+		req.ReadFetchModifiers()
+
+		if assert.Error(t, req.Err()) {
+			conn.Bad(req, req.Err())
+			assert.Equal(t, "t1 BAD "+tc.want+"\r\n", out.String())
+		}
+	}
+}
+
+func TestReadSelectQResync(t *testing.T) {
+	conn, _ := NewTestConn("t1 ENABLE QRESYNC\r\n" +
+		"t2 SELECT INBOX (QRESYNC (67890007 20050715194045000 41,43:211,214:541 (1,3,5 41,43,45)))")
+	conn.SetState(imap.StateAuthenticated)
+	conn.Capabilities().Enable(imap.ExtQResync)
+
+	req, _ := conn.ReadRequest()
+	caps := req.ReadEnable()
+	req.ReadEOL()
+	conn.Enable(req, caps)
+
+	req, _ = conn.ReadRequest()
+	mailbox, params := req.ReadSelect()
+	req.ReadEOL()
+
+	if !assert.NoError(t, req.Err()) || !assert.NotNil(t, params.QResync) {
+		return
+	}
+	assert.Equal(t, "INBOX", mailbox)
+	assert.Equal(t, 67890007, params.QResync.UIDValidity)
+	assert.Equal(t, uint64(20050715194045000), params.QResync.ModSeq)
+	assert.Equal(t, "41,43:211,214:541", params.QResync.KnownUIDs.String())
+	assert.Equal(t, "1,3,5", params.QResync.KnownSequenceSet.String())
+	assert.Equal(t, "41,43,45", params.QResync.KnownSequenceUIDs.String())
+}
+
+func TestReadSelectQResyncNotEnabled(t *testing.T) {
+	conn, out := NewTestConn("t1 SELECT INBOX (QRESYNC (1 2))\r\nt2 NOOP")
+	conn.SetState(imap.StateAuthenticated)
+
+	req, _ := conn.ReadRequest()
+	req.ReadSelect()
+	assert.Error(t, req.Err())
+	conn.Bad(req, req.Err())
+	assert.Equal(t, "t1 BAD QRESYNC must be enabled first\r\n", out.String())
+
+	expectNext(t, conn, "t2")
+}
+
+func TestSearchModSeq(t *testing.T) {
+	_, actual := NewParser(` MODSEQ "/flags/\\draft" all 620162338`).ReadSearch()
+
+	expected := &imap.IntTerm{
+		Op:    imap.OpGTE,
+		Field: imap.ModSeqField,
+		Int:   620162338,
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestSelectCondStoreParamEnables(t *testing.T) {
+	conn, out := NewTestConn("t1 SELECT INBOX (CONDSTORE)")
+	conn.SetState(imap.StateAuthenticated)
+	conn.Capabilities().Enable(imap.ExtCondStore)
+
+	req, _ := conn.ReadRequest()
+	_, params := req.ReadSelect()
+	req.ReadEOL()
+	if !assert.NoError(t, req.Err()) {
+		return
+	}
+	assert.True(t, params.CondStore)
+
+	conn.Select(req, namedMailbox("INBOX"), &imap.MailboxStatus{})
+	assert.True(t, conn.Enabled("CONDSTORE"))
+	assert.True(t, strings.Contains(out.String(), "* OK [NOMODSEQ] "), out.String())
+}
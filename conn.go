@@ -1,39 +1,86 @@
 package imap
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
+	"sync"
 )
 
 type Writer interface {
 	io.Writer
 
 	Splat(msg string)
+	Bye(msg string)
 	Continuation(msg string)
 	Ok(r *Request)
-	OkWithCode(r *Request, responseCode string)
+	OkWithCode(r *Request, code ResponseCode)
 	No(r *Request, err error)
+	NoWithCode(r *Request, code ResponseCode, err error)
 	Bad(r *Request, err error)
+	BadWithCode(r *Request, code ResponseCode, err error)
 }
 
 type Reader interface {
 	io.Reader
 }
 
+type ConnState int
+
+const (
+	StateNotAuthenticated ConnState = iota
+	StateAuthenticated
+	StateSelected
+	StateLogout
+)
+
 type Conn struct {
 	rwc    io.ReadWriteCloser
 	parser *Parser
+	caps   *CapabilityRegistry
+
+	mu           sync.Mutex
+	state        ConnState
+	searchResult *SequenceSet
+	enabled      map[string]bool // extensions turned on with ENABLE
+	namespaces   *Namespaces
+	rights       RightsFunc
+	selected     string // name of the selected mailbox
+	clientID     ID
 }
 
 func NewConn(rwc io.ReadWriteCloser) *Conn {
 	conn := &Conn{
-		rwc: rwc,
+		rwc:  rwc,
+		caps: DefaultCapabilities.Clone(),
 	}
 	conn.parser = NewParser(conn)
 
 	return conn
 }
 
+func (c *Conn) State() ConnState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *Conn) SetState(state ConnState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = state
+}
+
+// IsTLS reports whether the underlying transport is a TLS connection.
+func (c *Conn) IsTLS() bool {
+	rwc := c.rwc
+	if d, ok := rwc.(*deflateConn); ok {
+		rwc = d.rwc
+	}
+	_, ok := rwc.(*tls.Conn)
+	return ok
+}
+
 func (c *Conn) Write(b []byte) (int, error) {
 	return c.rwc.Write(b)
 }
@@ -46,36 +93,93 @@ func (c *Conn) Splat(msg string) {
 	fmt.Fprintf(c, "* %s\r\n", msg)
 }
 
+// Bye tells the client the server is closing the connection, either in
+// response to LOGOUT or unilaterally (shutdown, autologout).
+func (c *Conn) Bye(msg string) {
+	c.SetState(StateLogout)
+	fmt.Fprintf(c, "* BYE %s\r\n", msg)
+}
+
 func (c *Conn) Continuation(msg string) {
 	fmt.Fprintf(c, "+ %s\r\n", msg)
 }
 
 func (c *Conn) Ok(r *Request) {
-	fmt.Fprintf(c, "%s OK %s completed\r\n", r.Tag, r.Command)
+	fmt.Fprintf(c, "%s OK %s completed\r\n", r.Tag, r.Name())
 }
 
-func (c *Conn) OkWithCode(r *Request, responseCode string) {
-	fmt.Fprintf(c, "%s OK [%s] %s completed\r\n", r.Tag, responseCode, r.Command)
+func (c *Conn) OkWithCode(r *Request, code ResponseCode) {
+	fmt.Fprintf(c, "%s OK [%s] %s completed\r\n", r.Tag, code, r.Name())
 }
 
-func (c *Conn) No(r *Request, err error) {
-	tag := "*"
-	if r != nil {
-		tag = r.Tag
+// SplatOk sends an untagged OK carrying a response code, e.g.
+// `* OK [UIDVALIDITY 3857529045] UIDs valid`.
+func (c *Conn) SplatOk(code ResponseCode, msg string) {
+	if code.IsZero() {
+		c.Splat("OK " + msg)
+	} else {
+		c.Splat("OK [" + code.String() + "] " + msg)
 	}
+}
 
-	fmt.Fprintf(c, "%s NO %s\r\n", tag, err)
+// No sends a NO response. If err is a *ResponseError, its code is included.
+func (c *Conn) No(r *Request, err error) {
+	c.NoWithCode(r, responseCodeOf(err), err)
 }
 
+func (c *Conn) NoWithCode(r *Request, code ResponseCode, err error) {
+	c.status(r, "NO", code, err)
+}
+
+// Bad sends a BAD response. If err is a *ResponseError, its code is included.
 func (c *Conn) Bad(r *Request, err error) {
+	c.BadWithCode(r, responseCodeOf(err), err)
+}
+
+func (c *Conn) BadWithCode(r *Request, code ResponseCode, err error) {
+	c.status(r, "BAD", code, err)
+}
+
+func (c *Conn) status(r *Request, status string, code ResponseCode, err error) {
 	tag := "*"
 	if r != nil {
 		tag = r.Tag
 	}
 
-	fmt.Fprintf(c, "%s BAD %s\r\n", tag, err)
+	if code.IsZero() {
+		fmt.Fprintf(c, "%s %s %s\r\n", tag, status, err)
+	} else {
+		fmt.Fprintf(c, "%s %s [%s] %s\r\n", tag, status, code, err)
+	}
 }
 
 func (c *Conn) DiscardLine() {
 	c.parser.DiscardLine()
 }
+
+func (c *Conn) WriteFlags(flags []Flag) {
+	fmt.Fprintf(c, "* FLAGS %s\r\n", marshalFlagList(flags))
+}
+
+// WriteMailboxFlags sends the FLAGS and PERMANENTFLAGS responses for a
+// mailbox being selected.
+func (c *Conn) WriteMailboxFlags(mbox Mailbox) {
+	flags, perm := mailboxFlags(mbox)
+	c.WriteFlags(flags)
+	c.writePermanentFlags(perm)
+}
+
+func mailboxFlags(mbox Mailbox) (flags, perm []Flag) {
+	if f, ok := mbox.(MailboxFlagger); ok {
+		return f.Flags(), f.PermanentFlags()
+	}
+	return DefaultFlags, DefaultPermanentFlags
+}
+
+func (c *Conn) writePermanentFlags(perm []Flag) {
+	if len(perm) == 0 {
+		c.SplatOk(CodePermanentFlags(perm), "No permanent flags permitted")
+	} else {
+		c.SplatOk(CodePermanentFlags(perm), "Limited")
+	}
+}
@@ -0,0 +1,93 @@
+package imap
+
+import (
+	"strings"
+)
+
+// ENABLE (RFC 5161) and UTF8=ACCEPT (RFC 6855)
+
+var (
+	ExtEnable     = newExtension(CapAuthenticated, "ENABLE")
+	ExtUTF8Accept = newExtension(CapAuthenticated, "ENABLE", "UTF8=ACCEPT")
+)
+
+// enableable lists the capabilities ENABLE can turn on. Anything else is
+// silently ignored, as RFC 5161 requires.
+var enableable = map[string]bool{
+	"CONDSTORE":   true,
+	"QRESYNC":     true,
+	"UTF8=ACCEPT": true,
+}
+
+// ReadEnable reads the arguments of `ENABLE capability...`.
+func (p *Parser) ReadEnable() []string {
+	var caps []string
+	for p.Valid() && p.accept(" ") {
+		caps = append(caps, strings.ToUpper(p.ReadAtom()))
+	}
+	if !p.Valid() {
+		return nil
+	}
+	if len(caps) == 0 {
+		p.err = ProtocolError("ENABLE requires a capability")
+		return nil
+	}
+	return caps
+}
+
+// Enabled reports whether the client has enabled the named extension.
+func (c *Conn) Enabled(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled[strings.ToUpper(name)]
+}
+
+// Enable handles ENABLE: it turns on the requested extensions this server
+// supports, sends the ENABLED response and completes the request. Enabling
+// QRESYNC implies CONDSTORE.
+func (c *Conn) Enable(r *Request, caps []string) error {
+	if c.State() != StateAuthenticated {
+		return ProtocolError("ENABLE is only valid in the authenticated state")
+	}
+
+	var enabled []string
+	for _, name := range caps {
+		if name == "QRESYNC" && c.enable("CONDSTORE") {
+			enabled = append(enabled, "CONDSTORE")
+		}
+		if c.enable(name) {
+			enabled = append(enabled, name)
+		}
+	}
+
+	if len(enabled) == 0 {
+		c.Splat("ENABLED")
+	} else {
+		c.Splat("ENABLED " + strings.Join(enabled, " "))
+	}
+	c.Ok(r)
+	return nil
+}
+
+// enable turns on an extension the server supports and reports whether it
+// was newly enabled.
+func (c *Conn) enable(name string) bool {
+	if !enableable[name] || !c.HasCapability(name) || c.Enabled(name) {
+		return false
+	}
+
+	c.mu.Lock()
+	if c.enabled == nil {
+		c.enabled = map[string]bool{}
+	}
+	c.enabled[name] = true
+	c.mu.Unlock()
+
+	switch name {
+	case "UTF8=ACCEPT":
+		c.parser.utf8Accept = true
+	case "QRESYNC":
+		c.parser.qresync = true
+	}
+	return true
+}
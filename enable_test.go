@@ -0,0 +1,91 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnable(t *testing.T) {
+	conn, out := NewTestConn("t1 ENABLE UTF8=ACCEPT X-UNKNOWN QRESYNC\r\nt2 SELECT \"Entwürfe\"")
+	conn.SetState(imap.StateAuthenticated)
+	conn.Capabilities().Enable(imap.ExtUTF8Accept, imap.ExtQResync)
+
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	caps := req.ReadEnable()
+	req.ReadEOL()
+	assert.NoError(t, conn.Enable(req, caps))
+	assert.Equal(t, "* ENABLED UTF8=ACCEPT CONDSTORE QRESYNC\r\nt1 OK ENABLE completed\r\n", out.String())
+	assert.True(t, conn.Enabled("condstore"))
+	assert.False(t, conn.Enabled("X-UNKNOWN"))
+
+	req, err = conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.ReadSpace()
+	assert.Equal(t, "Entwürfe", req.ReadMailbox())
+	assert.NoError(t, req.Err())
+}
+
+func TestQuotedStringRejectsInvalidUTF8(t *testing.T) {
+	conn, _ := NewTestConn("t1 ENABLE UTF8=ACCEPT\r\nt2 SELECT \"\xff\"")
+	conn.SetState(imap.StateAuthenticated)
+	conn.Capabilities().Enable(imap.ExtUTF8Accept)
+
+	req, _ := conn.ReadRequest()
+	caps := req.ReadEnable()
+	req.ReadEOL()
+	conn.Enable(req, caps)
+
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.ReadSpace()
+	req.ReadMailbox()
+	assert.Error(t, req.Err())
+}
+
+func TestAppendUTF8(t *testing.T) {
+	conn, _ := NewTestConn("t1 ENABLE UTF8=ACCEPT\r\nt2 APPEND INBOX UTF8 (~{6+}\r\nhällo)")
+	conn.SetState(imap.StateAuthenticated)
+	conn.Capabilities().Enable(imap.ExtUTF8Accept)
+
+	req, _ := conn.ReadRequest()
+	caps := req.ReadEnable()
+	req.ReadEOL()
+	conn.Enable(req, caps)
+
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "INBOX", req.ReadAppendMailbox())
+
+	msg := req.ReadAppendMessage()
+	if assert.NotNil(t, msg) {
+		buf := make([]byte, 16)
+		n, _ := msg.Body.Read(buf)
+		assert.Equal(t, "hällo", string(buf[:n]))
+	}
+	assert.Nil(t, req.ReadAppendMessage())
+	req.ReadEOL()
+	assert.NoError(t, req.Err())
+}
+
+func TestEnableRequiresCapability(t *testing.T) {
+	conn, out := NewTestConn("t1 ENABLE CONDSTORE")
+	conn.SetState(imap.StateAuthenticated)
+
+	req, _ := conn.ReadRequest()
+	caps := req.ReadEnable()
+	req.ReadEOL()
+	assert.NoError(t, conn.Enable(req, caps))
+	assert.Equal(t, "* ENABLED\r\nt1 OK ENABLE completed\r\n", out.String())
+	assert.False(t, conn.Enabled("CONDSTORE"))
+}
@@ -0,0 +1,159 @@
+package imap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ESEARCH and SEARCHRES extensions (RFC 4731, RFC 5182)
+
+var (
+	ExtESearch   = newExtension(CapAuthenticated, "ESEARCH")
+	ExtSearchRes = newExtension(CapAuthenticated, "SEARCHRES")
+)
+
+type SearchReturn struct {
+	Min   bool
+	Max   bool
+	Count bool
+	All   bool
+	Save  bool
+}
+
+// ReadSearchWithReturn reads the arguments of SEARCH, accepting the optional
+// `RETURN (...)` clause. ret is nil if the client didn't send one, in which
+// case it expects a plain SEARCH response.
+func (p *Parser) ReadSearchWithReturn() (ret *SearchReturn, charset string, query Term) {
+	p.ReadSpace()
+	if p.accept("RETURN ") {
+		ret = p.readSearchReturn()
+		p.ReadSpace()
+		if !p.Valid() {
+			return nil, "", nil
+		}
+	}
+
+	charset, query = p.readSearchCriteria()
+	return ret, charset, query
+}
+
+func (p *Parser) readSearchReturn() *SearchReturn {
+	ret := &SearchReturn{}
+
+	p.ReadListStart()
+	if p.accept(")") {
+		// RETURN () is equivalent to RETURN (ALL)
+		p.listDepth--
+		ret.All = true
+		return ret
+	}
+
+	for {
+		opt := p.ReadAtom()
+		if !p.Valid() {
+			return nil
+		}
+
+		switch strings.ToUpper(opt) {
+		case "MIN":
+			ret.Min = true
+		case "MAX":
+			ret.Max = true
+		case "COUNT":
+			ret.Count = true
+		case "ALL":
+			ret.All = true
+		case "SAVE":
+			ret.Save = true
+		default:
+			p.err = ProtocolErrorf("unknown search return option %q", opt)
+			return nil
+		}
+
+		if !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+	return ret
+}
+
+// WriteSearch sends a plain SEARCH response. If modSeq is non-zero it is
+// included as the highest mod-sequence of the results (RFC 7162).
+func (c *Conn) WriteSearch(results []int, modSeq uint64) {
+	strs := make([]string, len(results))
+	for i, n := range results {
+		strs[i] = strconv.Itoa(n)
+	}
+
+	msg := "SEARCH"
+	if len(strs) > 0 {
+		msg += " " + strings.Join(strs, " ")
+	}
+	if modSeq > 0 && len(results) > 0 {
+		msg += fmt.Sprintf(" (MODSEQ %d)", modSeq)
+	}
+	c.Splat(msg)
+}
+
+// WriteESearch sends an ESEARCH response for the given return options.
+// Results must be sorted in ascending order. Nothing is sent if SAVE is the
+// only option, as RFC 5182 requires.
+func (c *Conn) WriteESearch(r *Request, ret *SearchReturn, results []int, modSeq uint64) {
+	if ret.Save && !ret.Min && !ret.Max && !ret.Count && !ret.All {
+		return
+	}
+
+	msg := fmt.Sprintf("ESEARCH (TAG %s)", quoteString(r.Tag))
+	if r.UID {
+		msg += " UID"
+	}
+
+	if len(results) > 0 {
+		if ret.Min {
+			msg += fmt.Sprintf(" MIN %d", results[0])
+		}
+		if ret.Max {
+			msg += fmt.Sprintf(" MAX %d", results[len(results)-1])
+		}
+	}
+	if ret.Count {
+		msg += fmt.Sprintf(" COUNT %d", len(results))
+	}
+	if ret.All && len(results) > 0 {
+		msg += " ALL " + NewSequenceSet(results).String()
+	}
+	if modSeq > 0 && len(results) > 0 {
+		msg += fmt.Sprintf(" MODSEQ %d", modSeq)
+	}
+
+	c.Splat(msg)
+}
+
+// SaveSearchResult stores the result of a SEARCH ... RETURN (SAVE), as UIDs.
+// Select and Unselect reset it.
+func (c *Conn) SaveSearchResult(uids *SequenceSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.searchResult = uids
+}
+
+// ResolveSequenceSet substitutes the saved search result for the `$`
+// marker. Other sets are returned unchanged. uids reports whether the
+// result holds UIDs rather than sequence numbers: the saved result always
+// does, even in a command without the UID prefix, so the caller must then
+// map it to sequence numbers itself (RFC 5182 §2.1).
+func (c *Conn) ResolveSequenceSet(r *Request, set *SequenceSet) (resolved *SequenceSet, uids bool) {
+	if !set.IsSaved() {
+		return set, r.UID
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.searchResult == nil {
+		return &SequenceSet{}, true
+	}
+	return c.searchResult, true
+}
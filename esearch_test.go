@@ -0,0 +1,83 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSearchWithReturn(t *testing.T) {
+	ret, cs, query := NewParser(` RETURN (MIN COUNT SAVE) CHARSET UTF-8 UNSEEN`).ReadSearchWithReturn()
+
+	assert.Equal(t, &imap.SearchReturn{Min: true, Count: true, Save: true}, ret)
+	assert.Equal(t, "UTF-8", cs)
+	assert.Equal(t, &imap.FlagTerm{Flag: imap.FlagSeen, Present: false}, query)
+}
+
+func TestSearchSavedResultMarker(t *testing.T) {
+	_, query := NewParser(` $`).ReadSearch()
+
+	term, ok := query.(*imap.SetTerm)
+	if assert.True(t, ok) {
+		assert.True(t, term.Set.IsSaved())
+	}
+
+	for _, input := range []string{" $:5", " $,1", " 1,$", " $x"} {
+		p := NewParser(input)
+		p.ReadSpace()
+		p.ReadSequenceSet()
+		assert.Error(t, p.Err(), input)
+	}
+
+	p := NewParser(" ($)")
+	p.ReadSpace()
+	p.ReadListStart()
+	assert.True(t, p.ReadSequenceSet().IsSaved())
+	p.ReadListEnd()
+	assert.NoError(t, p.Err())
+}
+
+func TestWriteESearch(t *testing.T) {
+	conn, out := NewTestConn("A282 UID SEARCH RETURN (MIN MAX COUNT ALL) FLAGGED")
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ret, _, _ := req.ReadSearchWithReturn()
+	conn.WriteESearch(req, ret, []int{2, 10, 11, 12, 13, 14, 15, 47}, 0)
+
+	assert.Equal(t, "* ESEARCH (TAG \"A282\") UID MIN 2 MAX 47 COUNT 8 ALL 2,10:15,47\r\n", out.String())
+}
+
+func TestResolveSequenceSet(t *testing.T) {
+	conn, _ := NewTestConn("a1 FETCH $ FLAGS\r\na2 UID FETCH 4:5 FLAGS\r\na3 FETCH 1 FLAGS")
+	conn.SetState(imap.StateAuthenticated)
+	conn.SaveSearchResult(imap.NewSequenceSet([]int{10, 12}))
+
+	req, _ := conn.ReadRequest()
+	req.ReadSpace()
+	set, uids := conn.ResolveSequenceSet(req, req.ReadSequenceSet())
+	assert.Equal(t, "10,12", set.String())
+	assert.True(t, uids, "the saved result holds UIDs even without the UID prefix")
+	req.DiscardLine()
+
+	req, _ = conn.ReadRequest()
+	req.ReadSpace()
+	set, uids = conn.ResolveSequenceSet(req, req.ReadSequenceSet())
+	assert.Equal(t, "4:5", set.String())
+	assert.True(t, uids)
+	req.DiscardLine()
+
+	conn.Select(req, namedMailbox("INBOX"), &imap.MailboxStatus{})
+	req, _ = conn.ReadRequest()
+	req.ReadSpace()
+	set, uids = conn.ResolveSequenceSet(req, imap.NewSequenceSet(nil))
+	assert.False(t, uids)
+
+	marker := NewParser(" $")
+	marker.ReadSpace()
+	set, _ = conn.ResolveSequenceSet(req, marker.ReadSequenceSet())
+	assert.Equal(t, 0, set.Len(), "SELECT resets the saved result")
+}
@@ -37,6 +37,8 @@ func parseFetchAttribute(field string) (FetchAttribute, error) {
 		return EnvelopeFetchAttribute, nil
 	case "UID":
 		return UIDFetchAttribute, nil
+	case "MODSEQ":
+		return ModSeqFetchAttribute, nil
 	}
 
 	return nil, ProtocolErrorf("unknown field %q", field)
@@ -95,6 +97,11 @@ func (fa BodyFetchAttribute) Name() string {
 	return fmt.Sprintf("BODY[%s]%s", sectionPart, partialPart)
 }
 
+// SetsSeen reports whether fetching the attribute implicitly sets \Seen.
+func (fa BodyFetchAttribute) SetsSeen() bool {
+	return !fa.peek
+}
+
 func (fa BodyFetchAttribute) Marshal(msg *Message) string {
 	result := ""
 	if fa.part == "" {
@@ -327,15 +334,29 @@ var (
 	RFC822SizeFetchAttribute   = BasicFetchAttribute{"RFC822.SIZE", MarshalRFC822Size}
 	RFC822TextFetchAttribute   = BasicFetchAttribute{"RFC822.TEXT", MarshalRFC822Text}
 	UIDFetchAttribute          = BasicFetchAttribute{"UID", MarshalUID}
+
+	// UTF8EnvelopeFetchAttribute replaces EnvelopeFetchAttribute once the
+	// client has enabled UTF8=ACCEPT
+	UTF8EnvelopeFetchAttribute = BasicFetchAttribute{"ENVELOPE", MarshalUTF8Envelope}
 )
 
+// MarshalEnvelope RFC 2047-encodes any 8-bit header text, so the result is
+// safe to send to clients that haven't enabled UTF8=ACCEPT.
 func MarshalEnvelope(m *Message) string {
-	return headerToEnvelopeString(m.Header)
+	return headerToEnvelopeString(m.Header, false)
+}
+
+func MarshalUTF8Envelope(m *Message) string {
+	return headerToEnvelopeString(m.Header, true)
 }
 
 func MarshalFlags(m *Message) string {
+	return marshalFlagList(m.Flags)
+}
+
+func marshalFlagList(list []Flag) string {
 	flags := []string{}
-	for _, f := range m.Flags {
+	for _, f := range list {
 		flags = append(flags, f.String())
 	}
 
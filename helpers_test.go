@@ -0,0 +1,49 @@
+package imap_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+// splitBuffer feeds a connection canned input and records its output
+// separately.
+type splitBuffer struct {
+	in  *bytes.Buffer
+	out bytes.Buffer
+}
+
+func (b *splitBuffer) Read(p []byte) (int, error)  { return b.in.Read(p) }
+func (b *splitBuffer) Write(p []byte) (int, error) { return b.out.Write(p) }
+func (b *splitBuffer) Close() error                { return nil }
+
+func NewTestConn(input string) (*imap.Conn, *bytes.Buffer) {
+	b := &splitBuffer{in: bytes.NewBufferString(input + "\r\n")}
+	return imap.NewConn(b), &b.out
+}
+
+// expectNext checks that the connection is in sync again after a failed
+// command, by reading the next one.
+func expectNext(t *testing.T, conn *imap.Conn, tag string) {
+	conn.DiscardLine()
+	req, err := conn.ReadRequest()
+	if assert.NoError(t, err) {
+		assert.Equal(t, tag, req.Tag)
+	}
+}
+
+// namedMailbox is a Mailbox with the default flags.
+type namedMailbox string
+
+func (m namedMailbox) Name() string     { return string(m) }
+func (m namedMailbox) UIDValidity() int { return 3857529045 }
+
+type flaggedMailbox struct {
+	namedMailbox
+	flags, perm []imap.Flag
+}
+
+func (m flaggedMailbox) Flags() []imap.Flag          { return m.flags }
+func (m flaggedMailbox) PermanentFlags() []imap.Flag { return m.perm }
@@ -0,0 +1,139 @@
+package imap
+
+import (
+	"sort"
+	"strings"
+)
+
+// ID extension (RFC 2971)
+
+var ExtID = newExtension(CapAlways, "ID")
+
+// Field names defined by RFC 2971 §3.3
+const (
+	IDName        = "name"
+	IDVersion     = "version"
+	IDOS          = "os"
+	IDOSVersion   = "os-version"
+	IDVendor      = "vendor"
+	IDSupportURL  = "support-url"
+	IDAddress     = "address"
+	IDDate        = "date"
+	IDCommand     = "command"
+	IDArguments   = "arguments"
+	IDEnvironment = "environment"
+)
+
+// ID identifies a client or server implementation. Field names are
+// case-insensitive and stored in lower case; fields sent as NIL are left
+// out.
+type ID map[string]string
+
+// Limits from RFC 2971 §3.3
+const (
+	maxIDFields      = 30
+	maxIDFieldLength = 30
+	maxIDValueLength = 1024
+)
+
+// ReadID reads the argument of `ID (field value ...)` or `ID NIL`. It
+// returns an empty, non-nil ID for NIL.
+func (p *Parser) ReadID() ID {
+	p.ReadSpace()
+	if !p.Valid() {
+		return nil
+	}
+
+	id := ID{}
+	if p.Peek() != '(' {
+		p.Expect("NIL")
+		if !p.Valid() {
+			return nil
+		}
+		return id
+	}
+
+	p.ReadListStart()
+	for n := 0; p.Valid(); n++ {
+		if n == maxIDFields {
+			p.err = ProtocolError("too many ID fields")
+			return nil
+		}
+
+		field := p.ReadString()
+		p.ReadSpace()
+		value, ok := p.readNString()
+		if !p.Valid() {
+			return nil
+		}
+		if len(field) > maxIDFieldLength || len(value) > maxIDValueLength {
+			p.err = ProtocolErrorf("ID field %q too long", field)
+			return nil
+		}
+		if ok {
+			id[strings.ToLower(field)] = value
+		}
+
+		if !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+	if !p.Valid() {
+		return nil
+	}
+	return id
+}
+
+// readNString reads a string or NIL, reporting false for NIL.
+func (p *Parser) readNString() (string, bool) {
+	if c := p.Peek(); c == '"' || c == '{' {
+		return p.ReadString(), true
+	}
+
+	p.Expect("NIL")
+	return "", false
+}
+
+func (id ID) String() string {
+	if len(id) == 0 {
+		return "NIL"
+	}
+
+	fields := make([]string, 0, len(id))
+	for field := range id {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	list := make([]string, len(fields))
+	for i, field := range fields {
+		list[i] = quoteString(field) + " " + quoteString(id[field])
+	}
+	return "(" + strings.Join(list, " ") + ")"
+}
+
+// ClientID returns the ID the client sent, or nil if it hasn't sent one.
+// Handlers can use it for logging, or to work around known client bugs.
+func (c *Conn) ClientID() ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clientID
+}
+
+// WriteID sends the ID response. A nil or empty ID is sent as NIL.
+func (c *Conn) WriteID(id ID) {
+	c.Splat("ID " + id.String())
+}
+
+// ID answers an ID command: it records the client's ID, replies with the
+// server's and completes the request.
+func (c *Conn) ID(r *Request, client, server ID) {
+	c.mu.Lock()
+	c.clientID = client
+	c.mu.Unlock()
+
+	c.WriteID(server)
+	c.Ok(r)
+}
@@ -0,0 +1,33 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadID(t *testing.T) {
+	id := NewParser(` ("Name" "Thunderbird" "version" "115.3" "os" NIL)`).ReadID()
+	assert.Equal(t, imap.ID{imap.IDName: "Thunderbird", imap.IDVersion: "115.3"}, id)
+
+	id = NewParser(` NIL`).ReadID()
+	assert.Equal(t, imap.ID{}, id)
+
+	p := NewParser(` ("name")`)
+	p.ReadID()
+	assert.Error(t, p.Err())
+}
+
+func TestIDCommand(t *testing.T) {
+	conn, out := NewTestConn(`a023 ID ("name" "sodr" "version" "19.34")`)
+	req, _ := conn.ReadRequest()
+	client := req.ReadID()
+	req.ReadEOL()
+	assert.NoError(t, req.Err())
+
+	conn.ID(req, client, imap.ID{imap.IDName: "go-imap", imap.IDVendor: "Example"})
+	assert.Equal(t, "* ID (\"name\" \"go-imap\" \"vendor\" \"Example\")\r\n"+
+		"a023 OK ID completed\r\n", out.String())
+	assert.Equal(t, "19.34", conn.ClientID()[imap.IDVersion])
+}
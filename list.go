@@ -0,0 +1,294 @@
+package imap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LIST and LSUB, including the LIST-EXTENDED (RFC 5258) and LIST-STATUS
+// (RFC 5819) extensions.
+
+var (
+	ExtListExtended = newExtension(CapAuthenticated, "LIST-EXTENDED")
+	ExtListStatus   = newExtension(CapAuthenticated, "LIST-STATUS")
+	ExtChildren     = newExtension(CapAuthenticated, "CHILDREN")
+)
+
+type MailboxAttr string
+
+const (
+	AttrNoInferiors   MailboxAttr = `\Noinferiors`
+	AttrNoSelect                  = `\Noselect`
+	AttrMarked                    = `\Marked`
+	AttrUnmarked                  = `\Unmarked`
+	AttrNonExistent               = `\NonExistent`
+	AttrSubscribed                = `\Subscribed`
+	AttrRemote                    = `\Remote`
+	AttrHasChildren               = `\HasChildren`
+	AttrHasNoChildren             = `\HasNoChildren`
+)
+
+type ListCommand struct {
+	Reference string
+	Patterns  []string
+
+	// Selection options
+	Subscribed     bool
+	Remote         bool
+	RecursiveMatch bool
+	SpecialUse     bool
+
+	// Return options
+	ReturnChildren   bool
+	ReturnSubscribed bool
+	ReturnSpecialUse bool
+	ReturnStatus     []string // status items requested by RETURN (STATUS (...))
+}
+
+// ReadListCommand reads the arguments of LIST, including LIST-EXTENDED
+// selection options, multiple patterns and return options.
+func (p *Parser) ReadListCommand() *ListCommand {
+	cmd := &ListCommand{}
+
+	p.ReadSpace()
+	if p.Peek() == '(' {
+		p.readListSelectOpts(cmd)
+		p.ReadSpace()
+	}
+
+	cmd.Reference = p.ReadMailbox()
+	p.ReadSpace()
+	if !p.Valid() {
+		return nil
+	}
+
+	if p.Peek() == '(' {
+		p.ReadListStart()
+		for {
+			cmd.Patterns = append(cmd.Patterns, p.ReadListMailbox())
+			if !p.Valid() || !p.accept(" ") {
+				break
+			}
+		}
+		p.ReadListEnd()
+	} else {
+		cmd.Patterns = []string{p.ReadListMailbox()}
+	}
+
+	if p.accept(" RETURN ") {
+		p.readListReturnOpts(cmd)
+	}
+
+	if !p.Valid() {
+		return nil
+	}
+	return cmd
+}
+
+func (p *Parser) readListSelectOpts(cmd *ListCommand) {
+	for _, opt := range p.readOptionList() {
+		switch opt {
+		case "SUBSCRIBED":
+			cmd.Subscribed = true
+		case "REMOTE":
+			cmd.Remote = true
+		case "RECURSIVEMATCH":
+			cmd.RecursiveMatch = true
+		case "SPECIAL-USE":
+			cmd.SpecialUse = true
+		default:
+			p.err = ProtocolErrorf("unknown list selection option %q", opt)
+			return
+		}
+	}
+
+	// RECURSIVEMATCH only makes sense together with an option that filters
+	if p.Valid() && cmd.RecursiveMatch && !cmd.Subscribed {
+		p.err = ProtocolError("RECURSIVEMATCH requires another selection option")
+	}
+}
+
+func (p *Parser) readListReturnOpts(cmd *ListCommand) {
+	p.ReadListStart()
+	if p.accept(")") {
+		p.listDepth--
+		return
+	}
+
+	for {
+		opt := strings.ToUpper(p.ReadAtom())
+		if !p.Valid() {
+			return
+		}
+
+		switch opt {
+		case "CHILDREN":
+			cmd.ReturnChildren = true
+		case "SUBSCRIBED":
+			cmd.ReturnSubscribed = true
+		case "SPECIAL-USE":
+			cmd.ReturnSpecialUse = true
+		case "STATUS":
+			p.ReadSpace()
+			cmd.ReturnStatus = p.readOptionList()
+		default:
+			p.err = ProtocolErrorf("unknown list return option %q", opt)
+			return
+		}
+
+		if !p.Valid() || !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+}
+
+// readOptionList reads a parenthesized, possibly empty, list of atoms and
+// upper-cases them.
+func (p *Parser) readOptionList() []string {
+	p.ReadListStart()
+	if !p.Valid() {
+		return nil
+	}
+
+	opts := []string{}
+	if p.accept(")") {
+		p.listDepth--
+		return opts
+	}
+
+	for {
+		opt := p.ReadAtom()
+		if !p.Valid() {
+			return nil
+		}
+		opts = append(opts, strings.ToUpper(opt))
+
+		if !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+	return opts
+}
+
+// ReadLsub reads the arguments of `LSUB reference pattern`.
+func (p *Parser) ReadLsub() (reference, pattern string) {
+	p.ReadSpace()
+	reference = p.ReadMailbox()
+	p.ReadSpace()
+	pattern = p.ReadListMailbox()
+	return
+}
+
+// Match reports whether a mailbox name matches any of the command's
+// patterns.
+func (cmd *ListCommand) Match(name string, delim byte) bool {
+	for _, pattern := range cmd.Patterns {
+		if MatchMailbox(name, cmd.Reference, pattern, delim) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchMailbox reports whether a mailbox name matches a LIST reference and
+// pattern. `*` matches any sequence of characters; `%` matches any sequence
+// that doesn't include the hierarchy delimiter. INBOX is matched
+// case-insensitively.
+func MatchMailbox(name, reference, pattern string, delim byte) bool {
+	if delim != 0 && strings.HasPrefix(pattern, string(delim)) {
+		reference = "" // an absolute pattern ignores the reference
+	}
+	full := reference + pattern
+
+	return matchWildcard(canonicalInbox(name, delim), canonicalInbox(full, delim), delim)
+}
+
+func canonicalInbox(name string, delim byte) string {
+	if len(name) >= 5 && strings.EqualFold(name[:5], "INBOX") &&
+		(len(name) == 5 || (delim != 0 && name[5] == delim)) {
+		return "INBOX" + name[5:]
+	}
+	return name
+}
+
+// matchWildcard matches name against a pattern containing * and %, which
+// matches anything but the hierarchy delimiter. It runs in
+// O(len(name)·len(pattern)): match[i] records whether the pattern so far
+// matches name[:i].
+func matchWildcard(name, pattern string, delim byte) bool {
+	match := make([]bool, len(name)+1)
+	next := make([]bool, len(name)+1)
+	match[0] = true
+
+	for k := 0; k < len(pattern); k++ {
+		switch c := pattern[k]; c {
+		case '*':
+			any := false
+			for i := range match {
+				any = any || match[i]
+				next[i] = any
+			}
+		case '%':
+			any := false
+			for i := range match {
+				if i > 0 && delim != 0 && name[i-1] == delim {
+					any = false
+				}
+				any = any || match[i]
+				next[i] = any
+			}
+		default:
+			next[0] = false
+			for i := 0; i < len(name); i++ {
+				next[i+1] = match[i] && name[i] == c
+			}
+		}
+		match, next = next, match
+	}
+	return match[len(name)]
+}
+
+// MailboxInfo is a single LIST or LSUB result.
+type MailboxInfo struct {
+	Attributes []MailboxAttr
+	Delimiter  byte   // zero for a flat namespace
+	Name       string // UTF-8; encoded as needed when written
+
+	// ChildInfo holds the selection options that matched only because of a
+	// child mailbox (CHILDINFO, used with RECURSIVEMATCH).
+	ChildInfo []string
+}
+
+func (info *MailboxInfo) format(name string) string {
+	attrs := make([]string, len(info.Attributes))
+	for i, a := range info.Attributes {
+		attrs[i] = string(a)
+	}
+
+	delim := "NIL"
+	if info.Delimiter != 0 {
+		delim = quoteString(string(info.Delimiter))
+	}
+
+	s := fmt.Sprintf("(%s) %s %s", strings.Join(attrs, " "), delim, quoteString(name))
+	if len(info.ChildInfo) > 0 {
+		opts := make([]string, len(info.ChildInfo))
+		for i, o := range info.ChildInfo {
+			opts[i] = quoteString(o)
+		}
+		s += fmt.Sprintf(` ("CHILDINFO" (%s))`, strings.Join(opts, " "))
+	}
+	return s
+}
+
+func (c *Conn) WriteList(info *MailboxInfo) {
+	c.Splat("LIST " + info.format(c.encodeMailbox(info.Name)))
+}
+
+func (c *Conn) WriteLsub(info *MailboxInfo) {
+	c.Splat("LSUB " + info.format(c.encodeMailbox(info.Name)))
+}
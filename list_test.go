@@ -0,0 +1,110 @@
+package imap_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadListCommandExtended(t *testing.T) {
+	p := NewParser(` (SUBSCRIBED RECURSIVEMATCH) "" ("INBOX" Drafts/%) RETURN (CHILDREN STATUS (MESSAGES UNSEEN))`)
+	cmd := p.ReadListCommand()
+	if !assert.NotNil(t, cmd, p.Err()) {
+		return
+	}
+
+	assert.True(t, cmd.Subscribed)
+	assert.True(t, cmd.RecursiveMatch)
+	assert.False(t, cmd.Remote)
+	assert.Equal(t, "", cmd.Reference)
+	assert.Equal(t, []string{"INBOX", "Drafts/%"}, cmd.Patterns)
+	assert.True(t, cmd.ReturnChildren)
+	assert.Equal(t, []string{"MESSAGES", "UNSEEN"}, cmd.ReturnStatus)
+}
+
+func TestReadListCommandBasic(t *testing.T) {
+	p := NewParser(` ~/Mail/ %`)
+	cmd := p.ReadListCommand()
+	if !assert.NotNil(t, cmd, p.Err()) {
+		return
+	}
+
+	assert.Equal(t, "~/Mail/", cmd.Reference)
+	assert.Equal(t, []string{"%"}, cmd.Patterns)
+}
+
+func TestMatchMailbox(t *testing.T) {
+	cases := []struct {
+		name, ref, pattern string
+		match              bool
+	}{
+		{"INBOX", "", "*", true},
+		{"inbox", "", "INBOX", true},
+		{"Archive/2024", "", "*", true},
+		{"Archive/2024", "", "%", false},
+		{"Archive/2024", "", "Archive/%", true},
+		{"Archive/2024/Q1", "", "Archive/%", false},
+		{"Archive/2024/Q1", "Archive/", "%/Q1", true},
+		{"Archive/2024", "Other/", "/Archive/*", false},
+		{"Sent", "", "S%t", true},
+		{"Sent", "", "S%", true},
+		{"Sent/Old", "", "S%d", false},
+		{"Sent/Old", "", "S*d", true},
+		{"Sent/Old", "", "%/%", true},
+		{"a/b/c", "", "*%", true},
+		{"", "", "%", true},
+		{"", "", "", true},
+		{"INBOX", "", "", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.match, imap.MatchMailbox(c.name, c.ref, c.pattern, '/'), c.name+" "+c.ref+c.pattern)
+	}
+}
+
+func TestMatchMailboxPathological(t *testing.T) {
+	name := strings.Repeat("a", 200)
+	pattern := strings.Repeat("*%", 100) + "b"
+
+	start := time.Now()
+	assert.False(t, imap.MatchMailbox(name, "", pattern, '/'))
+	assert.True(t, imap.MatchMailbox(name, "", pattern[:len(pattern)-1], '/'))
+	assert.True(t, time.Since(start) < time.Second, "matching took %v", time.Since(start))
+}
+
+func TestWriteList(t *testing.T) {
+	conn, out := NewTestConn("")
+	conn.WriteList(&imap.MailboxInfo{
+		Attributes: []imap.MailboxAttr{imap.AttrHasNoChildren},
+		Delimiter:  '/',
+		Name:       "Foo/Bar",
+		ChildInfo:  []string{"SUBSCRIBED"},
+	})
+	assert.Equal(t, "* LIST (\\HasNoChildren) \"/\" \"Foo/Bar\" (\"CHILDINFO\" (\"SUBSCRIBED\"))\r\n", out.String())
+}
+
+func TestReadCreateSpecialUse(t *testing.T) {
+	p := NewParser(` MySpecialArchive (USE (\archive \Junk))`)
+	mailbox, use := p.ReadCreate()
+	p.ReadEOL()
+
+	assert.NoError(t, p.Err())
+	assert.Equal(t, "MySpecialArchive", mailbox)
+	assert.Equal(t, []imap.MailboxAttr{imap.AttrArchive, imap.AttrJunk}, use)
+
+	p = NewParser(` Foo (USE (\Bogus))`)
+	p.ReadCreate()
+	assert.Error(t, p.Err())
+}
+
+func TestReadListSpecialUse(t *testing.T) {
+	p := NewParser(` (SPECIAL-USE) "" "*" RETURN (SPECIAL-USE)`)
+	cmd := p.ReadListCommand()
+	if assert.NotNil(t, cmd, p.Err()) {
+		assert.True(t, cmd.SpecialUse)
+		assert.True(t, cmd.ReturnSpecialUse)
+	}
+}
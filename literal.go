@@ -0,0 +1,43 @@
+package imap
+
+// Literal limits, including LITERAL- (RFC 7888)
+
+// maxLiteralMinus is the largest non-synchronizing literal allowed under
+// LITERAL-.
+const maxLiteralMinus = 4096
+
+var (
+	errLiteralMinus = &ResponseError{
+		Code: CodeTooBig,
+		Text: "non-synchronizing literals are limited to 4096 octets",
+		Bad:  true,
+	}
+	errLiteralBudget = NewResponseError(CodeLimit, "too much literal data")
+)
+
+// LiteralFilter is consulted before each literal is accepted. Returning
+// an error refuses the literal and fails the command with it; for a
+// synchronizing literal, the client is never asked to send the data.
+type LiteralFilter func(command string, size int, sync bool) error
+
+// SetLiteralMinus advertises LITERAL- instead of LITERAL+. Clients may
+// then only send non-synchronizing literals of up to 4096 octets; larger
+// ones are discarded and the command fails with `BAD [TOOBIG]`.
+func (c *Conn) SetLiteralMinus() {
+	c.caps.Unregister("LITERAL+")
+	c.caps.Register("LITERAL-", CapAlways)
+	c.parser.literalMinus = true
+}
+
+// SetLiteralBudget limits the total size of the literals in a single
+// command, other than APPEND messages (see SetMaxMessageSize). Commands
+// over budget fail with `NO [LIMIT]`. Zero means no limit.
+func (c *Conn) SetLiteralBudget(n int) {
+	c.parser.literalBudget = n
+}
+
+// SetLiteralFilter installs a filter that can refuse literals based on
+// the command and size.
+func (c *Conn) SetLiteralFilter(f LiteralFilter) {
+	c.parser.literalFilter = f
+}
@@ -0,0 +1,95 @@
+package imap_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiteralMinus(t *testing.T) {
+	conn, out := NewTestConn("a1 LOGIN {5000+}")
+	conn.SetLiteralMinus()
+	assert.True(t, conn.HasCapability("LITERAL-"))
+	assert.False(t, conn.HasCapability("LITERAL+"))
+
+	req, _ := conn.ReadRequest()
+	req.ReadSpace()
+	req.ReadAstring()
+	conn.Bad(req, req.Err())
+
+	assert.Equal(t, "a1 BAD [TOOBIG] non-synchronizing literals are limited to 4096 octets\r\n", out.String())
+}
+
+func TestLiteralBudget(t *testing.T) {
+	conn, out := NewTestConn("a1 LOGIN {4+}\r\nuser {9}")
+	conn.SetLiteralBudget(10)
+
+	req, _ := conn.ReadRequest()
+	req.ReadSpace()
+	assert.Equal(t, "user", req.ReadAstring())
+	req.ReadSpace()
+	req.ReadAstring()
+	conn.No(req, req.Err())
+
+	// the synchronizing literal is refused without a continuation
+	assert.Equal(t, "a1 NO [LIMIT] too much literal data\r\n", out.String())
+}
+
+func TestLiteralFilter(t *testing.T) {
+	conn, out := NewTestConn("a1 SETMETADATA INBOX (/shared/x {100}")
+	conn.SetLiteralFilter(func(command string, size int, sync bool) error {
+		if command == "SETMETADATA" && size > 64 {
+			return errors.New("value too large")
+		}
+		return nil
+	})
+
+	req, _ := conn.ReadRequest()
+	req.ReadSetMetadata()
+	conn.No(req, req.Err())
+
+	assert.Equal(t, "a1 NO value too large\r\n", out.String())
+}
+
+func TestLiteralMinusResyncs(t *testing.T) {
+	big := strings.Repeat("x", 5000)
+	conn, out := NewTestConn("a1 LOGIN {5000+}\r\n" + big + " {4+}\r\npass\r\na2 NOOP")
+	conn.SetLiteralMinus()
+
+	req, _ := conn.ReadRequest()
+	req.ReadSpace()
+	req.ReadAstring()
+	conn.Bad(req, req.Err())
+	conn.DiscardLine()
+
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "a2", req.Tag)
+	assert.Equal(t, "NOOP", req.Command)
+	req.ReadEOL()
+	assert.NoError(t, req.Err())
+	assert.Equal(t, "a1 BAD [TOOBIG] non-synchronizing literals are limited to 4096 octets\r\n", out.String())
+}
+
+func TestRefusedSyncLiteralEndsCommand(t *testing.T) {
+	conn, out := NewTestConn("a1 LOGIN {4+}\r\nuser {9}\r\na2 NOOP")
+	conn.SetLiteralBudget(10)
+
+	req, _ := conn.ReadRequest()
+	req.ReadSpace()
+	req.ReadAstring()
+	req.ReadSpace()
+	req.ReadAstring()
+	conn.No(req, req.Err())
+	conn.DiscardLine()
+
+	req, err := conn.ReadRequest()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "a2", req.Tag)
+	}
+	assert.Equal(t, "a1 NO [LIMIT] too much literal data\r\n", out.String())
+}
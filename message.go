@@ -1,6 +1,7 @@
 package imap
 
 import (
+	"strings"
 	"time"
 
 	"github.com/paulrosania/go-mail"
@@ -11,11 +12,15 @@ type Flag string
 const (
 	FlagAnswered Flag = `\Answered`
 	FlagFlagged       = `\Flagged`
-	FlagDeleted       = `\Trashed`
+	FlagDeleted       = `\Deleted`
 	FlagSeen          = `\Seen`
 	FlagDraft         = `\Draft`
 	FlagRecent        = `\Recent`
 
+	// FlagWildcard appears only in PERMANENTFLAGS, and means clients may
+	// create new keywords.
+	FlagWildcard Flag = `\*`
+
 	KeywordMDNSent       Flag = `$MDNSent`
 	KeywordForwarded          = `$Forwarded`
 	KeywordSubmitPending      = `$SubmitPending`
@@ -50,8 +55,62 @@ type Message struct {
 	UID        int
 	Flags      []Flag
 	ReceivedAt time.Time
+	ModSeq     uint64
 }
 
 func (f Flag) String() string {
 	return string(f)
 }
+
+// NormalizeFlag returns the canonical spelling of system flags (e.g.
+// `\SEEN` becomes `\Seen`). Keywords keep the case the client chose.
+func NormalizeFlag(f Flag) Flag {
+	if f.IsSystem() {
+		return Flag(normalize(string(f)))
+	}
+	return f
+}
+
+// Equal compares flags case-insensitively, as RFC 3501 requires.
+func (f Flag) Equal(other Flag) bool {
+	return strings.EqualFold(string(f), string(other))
+}
+
+// IsSystem reports whether f is a system flag (e.g. `\Seen`) rather than a
+// keyword (e.g. `$Junk`).
+func (f Flag) IsSystem() bool {
+	return strings.HasPrefix(string(f), `\`)
+}
+
+// DefaultFlags are the flags advertised in the FLAGS response of a mailbox
+// that doesn't declare its own.
+var DefaultFlags = []Flag{
+	FlagAnswered,
+	FlagFlagged,
+	FlagDeleted,
+	FlagSeen,
+	FlagDraft,
+}
+
+// DefaultPermanentFlags allows the system flags and arbitrary keywords to be
+// stored permanently.
+var DefaultPermanentFlags = []Flag{
+	FlagAnswered,
+	FlagFlagged,
+	FlagDeleted,
+	FlagSeen,
+	FlagDraft,
+	FlagWildcard,
+}
+
+// IsPermanent reports whether f can be stored permanently in a mailbox with
+// the given PERMANENTFLAGS. Keywords not listed are allowed if the list
+// includes `\*`.
+func IsPermanent(perm []Flag, f Flag) bool {
+	for _, p := range perm {
+		if p.Equal(f) || (p == FlagWildcard && !f.IsSystem()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,352 @@
+package imap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// METADATA extension (RFC 5464)
+
+var ExtMetadata = newExtension(CapAuthenticated, "METADATA")
+
+// MetadataDepthInfinity is the DEPTH option value "infinity".
+const MetadataDepthInfinity = -1
+
+func CodeMetadataLongEntries(size int) ResponseCode {
+	return ResponseCode{Name: "METADATA", Args: []string{"LONGENTRIES", strconv.Itoa(size)}}
+}
+
+func CodeMetadataMaxSize(size int) ResponseCode {
+	return ResponseCode{Name: "METADATA", Args: []string{"MAXSIZE", strconv.Itoa(size)}}
+}
+
+var (
+	CodeMetadataTooMany   = ResponseCode{Name: "METADATA", Args: []string{"TOOMANY"}}
+	CodeMetadataNoPrivate = ResponseCode{Name: "METADATA", Args: []string{"NOPRIVATE"}}
+)
+
+// MetadataEntry is an entry name and its value. A nil Value removes the
+// entry in SETMETADATA.
+type MetadataEntry struct {
+	Name  string
+	Value []byte
+}
+
+type GetMetadataOptions struct {
+	MaxSize int // zero for no limit
+	Depth   int // 0, 1 or MetadataDepthInfinity
+}
+
+// MetadataStore stores mailbox and server annotations. The mailbox name
+// is empty for server entries. Stores are per user: /private entries
+// belong to the user the store was opened for.
+type MetadataStore interface {
+	// GetMetadata returns the named entries that exist, and their
+	// descendants down to depth levels.
+	GetMetadata(mailbox string, entries []string, depth int) ([]MetadataEntry, error)
+
+	// SetMetadata sets or, for nil values, removes entries.
+	SetMetadata(mailbox string, entries []MetadataEntry) error
+}
+
+// ValidateEntryName checks an entry name's syntax: it must start with
+// /private or /shared, and can't contain wildcards, empty components or a
+// trailing slash. Names are case-insensitive; the lower-case form is
+// returned.
+func ValidateEntryName(name string) (string, error) {
+	lower := strings.ToLower(name)
+	if lower != "/private" && lower != "/shared" &&
+		!strings.HasPrefix(lower, "/private/") && !strings.HasPrefix(lower, "/shared/") {
+		return "", ProtocolErrorf("entry name %q must start with /private or /shared", name)
+	}
+	if strings.HasSuffix(lower, "/") || strings.Contains(lower, "//") {
+		return "", ProtocolErrorf("invalid entry name %q", name)
+	}
+	for i := 0; i < len(lower); i++ {
+		if c := lower[i]; c < 0x20 || c == 0x7f || c == '*' || c == '%' {
+			return "", ProtocolErrorf("invalid character %q in entry name %q", c, name)
+		}
+	}
+	return lower, nil
+}
+
+func (p *Parser) readEntryName() string {
+	name, err := ValidateEntryName(p.ReadAstring())
+	if !p.Valid() {
+		return ""
+	}
+	if err != nil {
+		p.err = err
+		return ""
+	}
+	return name
+}
+
+// ReadGetMetadata reads the arguments of
+// `GETMETADATA [(options)] mailbox entry` or `... mailbox (entries)`.
+func (p *Parser) ReadGetMetadata() (mailbox string, opts *GetMetadataOptions, entries []string) {
+	opts = &GetMetadataOptions{}
+
+	p.ReadSpace()
+	if p.Peek() == '(' {
+		p.readGetMetadataOptions(opts)
+		p.ReadSpace()
+	}
+
+	mailbox = p.ReadMailbox()
+	p.ReadSpace()
+	if !p.Valid() {
+		return "", nil, nil
+	}
+
+	if p.Peek() == '(' {
+		p.ReadListStart()
+		for {
+			entries = append(entries, p.readEntryName())
+			if !p.Valid() || !p.accept(" ") {
+				break
+			}
+		}
+		p.ReadListEnd()
+	} else {
+		entries = []string{p.readEntryName()}
+	}
+
+	if !p.Valid() {
+		return "", nil, nil
+	}
+	return mailbox, opts, entries
+}
+
+func (p *Parser) readGetMetadataOptions(opts *GetMetadataOptions) {
+	p.ReadListStart()
+	for p.Valid() {
+		if p.accept("MAXSIZE ") {
+			opts.MaxSize = p.ReadInt()
+		} else if p.accept("DEPTH ") {
+			switch depth := strings.ToLower(p.ReadAtom()); depth {
+			case "0":
+				opts.Depth = 0
+			case "1":
+				opts.Depth = 1
+			case "infinity":
+				opts.Depth = MetadataDepthInfinity
+			default:
+				if p.Valid() {
+					p.err = ProtocolErrorf("invalid metadata depth %q", depth)
+				}
+			}
+		} else {
+			p.err = ProtocolErrorf("unknown metadata option near %q", p.Tail())
+		}
+
+		if !p.Valid() || !p.accept(" ") {
+			break
+		}
+	}
+	p.ReadListEnd()
+}
+
+// ReadSetMetadata reads the arguments of
+// `SETMETADATA mailbox (entry value ...)`.
+func (p *Parser) ReadSetMetadata() (mailbox string, entries []MetadataEntry) {
+	p.ReadSpace()
+	mailbox = p.ReadMailbox()
+	p.ReadSpace()
+	p.ReadListStart()
+
+	for p.Valid() {
+		e := MetadataEntry{Name: p.readEntryName()}
+		p.ReadSpace()
+		e.Value = p.readMetadataValue()
+		entries = append(entries, e)
+
+		if !p.Valid() || !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+	if !p.Valid() {
+		return "", nil
+	}
+	return mailbox, entries
+}
+
+// readMetadataValue reads an nstring or literal8. NIL reads as nil.
+func (p *Parser) readMetadataValue() []byte {
+	switch p.Peek() {
+	case '~':
+		p.advance(1)
+		return []byte(p.ReadLiteral())
+	case '"', '{':
+		return []byte(p.ReadString())
+	}
+
+	atom := p.ReadAtom()
+	if p.Valid() && !strings.EqualFold(atom, "NIL") {
+		p.err = ProtocolErrorf("invalid metadata value %q", atom)
+	}
+	return nil
+}
+
+func formatMetadataValue(value []byte) string {
+	if value == nil {
+		return "NIL"
+	}
+	for _, c := range value {
+		if c < 0x20 || c > 0x7e {
+			return fmt.Sprintf("{%d}\r\n%s", len(value), value)
+		}
+	}
+	return quoteString(string(value))
+}
+
+// WriteMetadata sends a METADATA response with entry values.
+func (c *Conn) WriteMetadata(mailbox string, entries []MetadataEntry) {
+	list := make([]string, len(entries))
+	for i, e := range entries {
+		list[i] = quoteString(e.Name) + " " + formatMetadataValue(e.Value)
+	}
+	c.Splat(fmt.Sprintf("METADATA %s (%s)", quoteString(c.encodeMailbox(mailbox)), strings.Join(list, " ")))
+}
+
+// GetMetadata answers a GETMETADATA command from the store. Entries larger
+// than MAXSIZE are left out and reported with [METADATA LONGENTRIES n].
+func (c *Conn) GetMetadata(r *Request, store MetadataStore, mailbox string, opts *GetMetadataOptions, entries []string) error {
+	found, err := store.GetMetadata(mailbox, entries, opts.Depth)
+	if err != nil {
+		return err
+	}
+
+	longest := 0
+	var result []MetadataEntry
+	for _, e := range found {
+		if opts.MaxSize > 0 && len(e.Value) > opts.MaxSize {
+			if len(e.Value) > longest {
+				longest = len(e.Value)
+			}
+			continue
+		}
+		result = append(result, e)
+	}
+
+	if len(result) > 0 {
+		c.WriteMetadata(mailbox, result)
+	}
+	if longest > 0 {
+		c.OkWithCode(r, CodeMetadataLongEntries(longest))
+	} else {
+		c.Ok(r)
+	}
+	return nil
+}
+
+// MemoryMetadataStore is a MetadataStore that keeps entries in memory. The
+// zero value is ready to use.
+type MemoryMetadataStore struct {
+	// MaxEntries limits the number of entries per mailbox; zero means no
+	// limit. SETMETADATA beyond it fails with [METADATA TOOMANY].
+	MaxEntries int
+
+	// MaxSize limits the size of a value; zero means no limit.
+	MaxSize int
+
+	mu      sync.Mutex
+	entries map[string]map[string][]byte
+}
+
+func NewMemoryMetadataStore() *MemoryMetadataStore {
+	return &MemoryMetadataStore{entries: map[string]map[string][]byte{}}
+}
+
+func (s *MemoryMetadataStore) GetMetadata(mailbox string, names []string, depth int) ([]MetadataEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.entries[mailbox]
+	seen := map[string]bool{}
+	var result []MetadataEntry
+	for _, name := range names {
+		var matches []string
+		for entry := range stored {
+			if !seen[entry] && entryWithinDepth(entry, name, depth) {
+				matches = append(matches, entry)
+			}
+		}
+		sort.Strings(matches)
+
+		for _, entry := range matches {
+			seen[entry] = true
+			result = append(result, MetadataEntry{Name: entry, Value: stored[entry]})
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryMetadataStore) SetMetadata(mailbox string, entries []MetadataEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range entries {
+		if s.MaxSize > 0 && len(e.Value) > s.MaxSize {
+			return NewResponseError(CodeMetadataMaxSize(s.MaxSize), "value too large")
+		}
+	}
+
+	stored := s.entries[mailbox]
+	if stored == nil {
+		stored = map[string][]byte{}
+	}
+
+	// Check the final count before changing anything, so the command
+	// either succeeds or fails as a whole. A name given more than once
+	// ends up with its last value.
+	final := map[string]bool{}
+	for _, e := range entries {
+		final[e.Name] = e.Value != nil
+	}
+	count := len(stored)
+	for name, set := range final {
+		_, exists := stored[name]
+		if !set && exists {
+			count--
+		} else if set && !exists {
+			count++
+		}
+	}
+	if s.MaxEntries > 0 && count > s.MaxEntries {
+		return NewResponseError(CodeMetadataTooMany, "too many metadata entries")
+	}
+
+	for _, e := range entries {
+		if e.Value == nil {
+			delete(stored, e.Name)
+		} else {
+			stored[e.Name] = append([]byte(nil), e.Value...)
+		}
+	}
+	if s.entries == nil {
+		s.entries = map[string]map[string][]byte{}
+	}
+	s.entries[mailbox] = stored
+	return nil
+}
+
+// entryWithinDepth reports whether entry is name itself or a descendant
+// at most depth levels below it.
+func entryWithinDepth(entry, name string, depth int) bool {
+	if entry == name {
+		return true
+	}
+	if depth == 0 || !strings.HasPrefix(entry, name+"/") {
+		return false
+	}
+	if depth == MetadataDepthInfinity {
+		return true
+	}
+	return strings.Count(entry[len(name)+1:], "/") < depth
+}
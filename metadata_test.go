@@ -0,0 +1,68 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadGetMetadata(t *testing.T) {
+	mailbox, opts, entries := NewParser(` (MAXSIZE 1024 DEPTH infinity) INBOX (/shared/Comment /private/vendor/x)`).ReadGetMetadata()
+
+	assert.Equal(t, "INBOX", mailbox)
+	assert.Equal(t, &imap.GetMetadataOptions{MaxSize: 1024, Depth: imap.MetadataDepthInfinity}, opts)
+	assert.Equal(t, []string{"/shared/comment", "/private/vendor/x"}, entries)
+}
+
+func TestEntryNameSyntax(t *testing.T) {
+	for _, name := range []string{"/comment", "/shared/", "/shared//x", "/private/*", "shared/x"} {
+		_, err := imap.ValidateEntryName(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestReadSetMetadata(t *testing.T) {
+	mailbox, entries := NewParser(` INBOX (/private/comment "My comment" /shared/comment NIL)`).ReadSetMetadata()
+
+	assert.Equal(t, "INBOX", mailbox)
+	assert.Equal(t, []imap.MetadataEntry{
+		{Name: "/private/comment", Value: []byte("My comment")},
+		{Name: "/shared/comment", Value: nil},
+	}, entries)
+}
+
+func TestMemoryMetadataStore(t *testing.T) {
+	store := imap.NewMemoryMetadataStore()
+	store.MaxEntries = 3
+	err := store.SetMetadata("INBOX", []imap.MetadataEntry{
+		{Name: "/private/filters/values", Value: []byte("a")},
+		{Name: "/private/filters/values/small", Value: []byte("a very long value")},
+		{Name: "/private/comment", Value: []byte("hi")},
+	})
+	assert.NoError(t, err)
+
+	err = store.SetMetadata("INBOX", []imap.MetadataEntry{{Name: "/shared/comment", Value: []byte("x")}})
+	assert.Equal(t, imap.CodeMetadataTooMany, err.(*imap.ResponseError).Code)
+
+	conn, out := NewTestConn("a GETMETADATA (MAXSIZE 5 DEPTH 1) INBOX /private/filters/values")
+	req, _ := conn.ReadRequest()
+	mailbox, opts, entries := req.ReadGetMetadata()
+	assert.NoError(t, conn.GetMetadata(req, store, mailbox, opts, entries))
+
+	assert.Equal(t, "* METADATA \"INBOX\" (\"/private/filters/values\" \"a\")\r\n"+
+		"a OK [METADATA LONGENTRIES 17] GETMETADATA completed\r\n", out.String())
+}
+
+func TestMemoryMetadataStoreZeroValue(t *testing.T) {
+	store := &imap.MemoryMetadataStore{MaxEntries: 1}
+	err := store.SetMetadata("INBOX", []imap.MetadataEntry{
+		{Name: "/private/comment", Value: []byte("a")},
+		{Name: "/private/comment", Value: []byte("b")},
+	})
+	assert.NoError(t, err)
+
+	got, err := store.GetMetadata("INBOX", []string{"/private/comment"}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []imap.MetadataEntry{{Name: "/private/comment", Value: []byte("b")}}, got)
+}
@@ -0,0 +1,86 @@
+package imap
+
+// MOVE extension (RFC 6851)
+
+var ExtMove = newExtension(CapAuthenticated, "MOVE")
+
+// MailboxMover is implemented by backends that can move messages
+// atomically.
+type MailboxMover interface {
+	Mailbox
+
+	// Move moves messages to the named mailbox. It returns the UIDs of the
+	// moved messages in both mailboxes and the sequence numbers that were
+	// expunged from this one, in the order they should be reported.
+	Move(seqs *SequenceSet, uid bool, dest string) (res *CopyResult, expunged []int, err error)
+}
+
+var errMoveUnsupported = NewResponseError(CodeCannot, "MOVE not supported by this mailbox")
+
+// ReadMove reads the arguments of `MOVE <sequence-set> <mailbox>`.
+func (p *Parser) ReadMove() (set *SequenceSet, mailbox string) {
+	p.ReadSpace()
+	set = p.ReadSequenceSet()
+	p.ReadSpace()
+	mailbox = p.ReadMailbox()
+	return
+}
+
+// Move moves messages through the backend and writes the response sequence:
+// an untagged OK with COPYUID, the untagged EXPUNGEs and the tagged OK.
+// Mailboxes that aren't a MailboxMover but can copy, store flags and expunge
+// get the equivalent of COPY, STORE +FLAGS.SILENT (\Deleted) and
+// UID EXPUNGE (RFC 6851 §3.3), which isn't atomic. The user needs the "i"
+// right on dest and "t" and "e" on the source mailbox.
+func (c *Conn) Move(r *Request, mbox Mailbox, seqs *SequenceSet, dest string) error {
+	if err := c.Authorize(dest, CommandRights("MOVE")); err != nil {
+		return err
+	}
+	if err := c.Authorize(mbox.Name(), "te"); err != nil {
+		return err
+	}
+	var res *CopyResult
+	var expunged []int
+	var err error
+	if mover, ok := mbox.(MailboxMover); ok {
+		res, expunged, err = mover.Move(seqs, r.UID, dest)
+	} else {
+		res, expunged, err = copyAndExpunge(mbox, seqs, r.UID, dest)
+	}
+	if err != nil {
+		return err
+	}
+
+	if res != nil && res.Source.Len() > 0 {
+		c.SplatOk(res.ResponseCode(), "Moved")
+	}
+	for _, seq := range expunged {
+		c.WriteExpunge(seq)
+	}
+	c.Ok(r)
+	return nil
+}
+
+func copyAndExpunge(mbox Mailbox, seqs *SequenceSet, uid bool, dest string) (*CopyResult, []int, error) {
+	copier, ok1 := mbox.(MailboxCopier)
+	storer, ok2 := mbox.(MailboxStorer)
+	expunger, ok3 := mbox.(MailboxExpunger)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, nil, errMoveUnsupported
+	}
+
+	res, err := copier.Copy(seqs, uid, dest)
+	if err != nil || res == nil || res.Source.Len() == 0 {
+		return res, nil, err
+	}
+
+	del := &StoreCommand{Set: res.Source, Op: StoreAdd, Silent: true, Flags: []Flag{FlagDeleted}}
+	if err := storer.Store(res.Source, true, del); err != nil {
+		return nil, nil, err
+	}
+	expunged, err := expunger.Expunge(res.Source)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, expunged, nil
+}
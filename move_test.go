@@ -0,0 +1,111 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMove(t *testing.T) {
+	conn, _ := NewTestConn("a1 UID MOVE 42:69 foo")
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "UID MOVE", req.Name())
+	set, mailbox := req.ReadMove()
+	req.ReadEOL()
+	assert.NoError(t, req.Err())
+	assert.Equal(t, "42:69", set.String())
+	assert.Equal(t, "foo", mailbox)
+}
+
+type moverMailbox struct {
+	namedMailbox
+	uid  bool
+	dest string
+}
+
+func (m *moverMailbox) Move(seqs *imap.SequenceSet, uid bool, dest string) (*imap.CopyResult, []int, error) {
+	m.uid, m.dest = uid, dest
+	res := &imap.CopyResult{
+		UIDValidity: 432432,
+		Source:      imap.NewSequenceSet([]int{42, 43}),
+		Dest:        imap.NewSequenceSet([]int{1202, 1203}),
+	}
+	return res, []int{3, 3}, nil
+}
+
+func TestMove(t *testing.T) {
+	conn, out := NewTestConn("a1 UID MOVE 42:43 foo")
+	req, _ := conn.ReadRequest()
+	set, dest := req.ReadMove()
+	req.ReadEOL()
+
+	mbox := &moverMailbox{namedMailbox: "INBOX"}
+	assert.NoError(t, conn.Move(req, mbox, set, dest))
+	assert.True(t, mbox.uid)
+	assert.Equal(t, "foo", mbox.dest)
+	assert.Equal(t, "* OK [COPYUID 432432 42:43 1202:1203] Moved\r\n"+
+		"* 3 EXPUNGE\r\n"+
+		"* 3 EXPUNGE\r\n"+
+		"a1 OK UID MOVE completed\r\n", out.String())
+}
+
+// copyingMailbox can't move, but can copy, flag and expunge.
+type copyingMailbox struct {
+	namedMailbox
+	deleted []string
+	calls   []string
+}
+
+func (m *copyingMailbox) Copy(seqs *imap.SequenceSet, uid bool, dest string) (*imap.CopyResult, error) {
+	m.calls = append(m.calls, "copy")
+	return &imap.CopyResult{
+		UIDValidity: 432432,
+		Source:      imap.NewSequenceSet([]int{42, 43}),
+		Dest:        imap.NewSequenceSet([]int{1202, 1203}),
+	}, nil
+}
+
+func (m *copyingMailbox) Store(seqs *imap.SequenceSet, uid bool, cmd *imap.StoreCommand) error {
+	m.calls = append(m.calls, "store")
+	if uid && cmd.Op == imap.StoreAdd && len(cmd.Flags) == 1 && cmd.Flags[0] == imap.FlagDeleted {
+		m.deleted = append(m.deleted, seqs.String())
+	}
+	return nil
+}
+
+func (m *copyingMailbox) Expunge(uids *imap.SequenceSet) ([]int, error) {
+	m.calls = append(m.calls, "expunge "+uids.String())
+	return []int{5, 5}, nil
+}
+
+func TestMoveFallsBackToCopy(t *testing.T) {
+	conn, out := NewTestConn("a1 MOVE 5:6 foo")
+	req, _ := conn.ReadRequest()
+	set, dest := req.ReadMove()
+	req.ReadEOL()
+
+	mbox := &copyingMailbox{namedMailbox: "INBOX"}
+	assert.NoError(t, conn.Move(req, mbox, set, dest))
+	assert.Equal(t, []string{"copy", "store", "expunge 42:43"}, mbox.calls)
+	assert.Equal(t, []string{"42:43"}, mbox.deleted)
+	assert.Equal(t, "* OK [COPYUID 432432 42:43 1202:1203] Moved\r\n"+
+		"* 5 EXPUNGE\r\n"+
+		"* 5 EXPUNGE\r\n"+
+		"a1 OK MOVE completed\r\n", out.String())
+}
+
+func TestMoveUnsupported(t *testing.T) {
+	conn, out := NewTestConn("a1 MOVE 1 foo")
+	req, _ := conn.ReadRequest()
+	set, dest := req.ReadMove()
+	req.ReadEOL()
+
+	err := conn.Move(req, namedMailbox("INBOX"), set, dest)
+	conn.No(req, err)
+	assert.Equal(t, "a1 NO [CANNOT] MOVE not supported by this mailbox\r\n", out.String())
+}
@@ -0,0 +1,166 @@
+package imap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NAMESPACE extension (RFC 2342)
+
+var ExtNamespace = newExtension(CapAuthenticated, "NAMESPACE")
+
+type NamespaceKind int
+
+const (
+	NamespacePersonal NamespaceKind = iota
+	NamespaceOtherUsers
+	NamespaceShared
+)
+
+// Namespace is a mailbox name prefix, e.g. "#shared/". Delimiter is zero
+// for a flat namespace.
+type Namespace struct {
+	Prefix    string
+	Delimiter byte
+}
+
+// Namespaces describes the namespaces available to a user. Each list may
+// be empty.
+type Namespaces struct {
+	Personal   []Namespace
+	OtherUsers []Namespace
+	Shared     []Namespace
+}
+
+// DefaultNamespaces is a single personal namespace with no prefix, which
+// is what clients assume of servers without NAMESPACE.
+var DefaultNamespaces = &Namespaces{
+	Personal: []Namespace{{Prefix: "", Delimiter: '/'}},
+}
+
+// MailboxRef is a mailbox name resolved against a set of namespaces.
+type MailboxRef struct {
+	Kind      NamespaceKind
+	Namespace Namespace
+
+	// Owner is the user whose mailbox this is, for the other users'
+	// namespace; it is the first hierarchy level after the prefix.
+	Owner string
+
+	// Name is the rest of the name, relative to the namespace (and owner).
+	// It is empty for the namespace root itself.
+	Name string
+}
+
+// Resolve finds the namespace a mailbox name belongs to. The longest
+// matching prefix wins; INBOX is always personal. It returns nil if the
+// name is outside every namespace.
+func (ns *Namespaces) Resolve(name string) *MailboxRef {
+	if strings.EqualFold(name, "INBOX") {
+		return &MailboxRef{Kind: NamespacePersonal, Name: "INBOX"}
+	}
+
+	var best *MailboxRef
+	bestLen := -1
+	try := func(kind NamespaceKind, list []Namespace) {
+		for _, n := range list {
+			rest, ok := n.trim(name)
+			if !ok || len(n.Prefix) <= bestLen {
+				continue
+			}
+			best = &MailboxRef{Kind: kind, Namespace: n, Name: rest}
+			bestLen = len(n.Prefix)
+		}
+	}
+	try(NamespacePersonal, ns.Personal)
+	try(NamespaceOtherUsers, ns.OtherUsers)
+	try(NamespaceShared, ns.Shared)
+
+	if best != nil && best.Kind == NamespaceOtherUsers {
+		best.Owner, best.Name = best.Name, ""
+		if d := best.Namespace.Delimiter; d != 0 {
+			if i := strings.IndexByte(best.Owner, d); i >= 0 {
+				best.Owner, best.Name = best.Owner[:i], best.Owner[i+1:]
+			}
+		}
+	}
+	return best
+}
+
+// trim removes the namespace prefix from name. A name equal to the prefix
+// without its trailing delimiter refers to the namespace root.
+func (n Namespace) trim(name string) (string, bool) {
+	if strings.HasPrefix(name, n.Prefix) {
+		return name[len(n.Prefix):], true
+	}
+	if n.Delimiter != 0 && name+string(n.Delimiter) == n.Prefix {
+		return "", true
+	}
+	return "", false
+}
+
+// SetNamespaces configures the namespaces reported by NAMESPACE and used
+// by ResolveMailbox.
+func (c *Conn) SetNamespaces(ns *Namespaces) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.namespaces = ns
+}
+
+func (c *Conn) Namespaces() *Namespaces {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.namespaces == nil {
+		return DefaultNamespaces
+	}
+	return c.namespaces
+}
+
+// ResolveMailbox resolves a mailbox name read from the client against the
+// connection's namespaces.
+func (c *Conn) ResolveMailbox(name string) (*MailboxRef, error) {
+	ref := c.Namespaces().Resolve(name)
+	if ref == nil {
+		return nil, NewResponseError(CodeNonExistent, "no such namespace")
+	}
+	return ref, nil
+}
+
+// LookupMailbox resolves a mailbox name and fetches it from the backend.
+func (c *Conn) LookupMailbox(b MailboxLocator, name string) (Mailbox, error) {
+	ref, err := c.ResolveMailbox(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.Mailbox(ref)
+}
+
+func (c *Conn) formatNamespaces(list []Namespace) string {
+	if len(list) == 0 {
+		return "NIL"
+	}
+
+	strs := make([]string, len(list))
+	for i, n := range list {
+		delim := "NIL"
+		if n.Delimiter != 0 {
+			delim = quoteString(string(n.Delimiter))
+		}
+		strs[i] = fmt.Sprintf("(%s %s)", quoteString(c.encodeMailbox(n.Prefix)), delim)
+	}
+	return "(" + strings.Join(strs, "") + ")"
+}
+
+// WriteNamespace sends the NAMESPACE response.
+func (c *Conn) WriteNamespace(ns *Namespaces) {
+	c.Splat(fmt.Sprintf("NAMESPACE %s %s %s",
+		c.formatNamespaces(ns.Personal),
+		c.formatNamespaces(ns.OtherUsers),
+		c.formatNamespaces(ns.Shared)))
+}
+
+// Namespace answers a NAMESPACE command with the connection's namespaces.
+func (c *Conn) Namespace(r *Request) {
+	c.WriteNamespace(c.Namespaces())
+	c.Ok(r)
+}
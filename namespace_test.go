@@ -0,0 +1,47 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNamespaces = &imap.Namespaces{
+	Personal:   []imap.Namespace{{Prefix: "", Delimiter: '/'}},
+	OtherUsers: []imap.Namespace{{Prefix: "~", Delimiter: '/'}},
+	Shared:     []imap.Namespace{{Prefix: "#shared/", Delimiter: '/'}},
+}
+
+func TestWriteNamespace(t *testing.T) {
+	conn, out := NewTestConn("")
+	conn.WriteNamespace(testNamespaces)
+
+	assert.Equal(t, "* NAMESPACE ((\"\" \"/\")) ((\"~\" \"/\")) ((\"#shared/\" \"/\"))\r\n", out.String())
+
+	out.Reset()
+	conn.WriteNamespace(&imap.Namespaces{Personal: []imap.Namespace{{Prefix: "", Delimiter: '.'}}})
+	assert.Equal(t, "* NAMESPACE ((\"\" \".\")) NIL NIL\r\n", out.String())
+}
+
+func TestResolveNamespace(t *testing.T) {
+	ref := testNamespaces.Resolve("#shared/support/2024")
+	assert.Equal(t, imap.NamespaceShared, ref.Kind)
+	assert.Equal(t, "support/2024", ref.Name)
+
+	ref = testNamespaces.Resolve("~alice/Sent")
+	assert.Equal(t, imap.NamespaceOtherUsers, ref.Kind)
+	assert.Equal(t, "alice", ref.Owner)
+	assert.Equal(t, "Sent", ref.Name)
+
+	ref = testNamespaces.Resolve("#shared")
+	assert.Equal(t, imap.NamespaceShared, ref.Kind)
+	assert.Equal(t, "", ref.Name)
+
+	ref = testNamespaces.Resolve("Archive/2023")
+	assert.Equal(t, imap.NamespacePersonal, ref.Kind)
+	assert.Equal(t, "Archive/2023", ref.Name)
+
+	ref = (&imap.Namespaces{Shared: testNamespaces.Shared}).Resolve("Drafts")
+	assert.Nil(t, ref)
+}
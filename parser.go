@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"io/ioutil"
 )
@@ -37,6 +38,32 @@ type Parser struct {
 	inSection   bool
 	listDepth   int
 
+	// literalStream is the most recent literal handed out by
+	// ReadLiteralStream. Whatever the caller leaves unread is discarded
+	// before the next line is read.
+	literalStream  *io.LimitedReader
+	maxMessageSize int
+	utf8Accept     bool
+	qresync        bool // QRESYNC has been enabled
+	condStore      bool // the current SELECT or EXAMINE has the CONDSTORE parameter
+	utf8Append     bool // a UTF8 (...) append data item needs closing
+	readOnly       bool // the selected mailbox is read-only; BODY acts as BODY.PEEK
+
+	literalMinus  bool
+	literalBudget int // total literal octets allowed per command, or 0
+	literalUsed   int
+	literalFilter LiteralFilter
+	command       string
+	uid           bool // the command has a UID prefix
+
+	// literalRefused is set when the current line's synchronizing literal
+	// was refused, so the client won't send it.
+	literalRefused bool
+
+	// checkQuota, if set, vets each APPEND message's size before the client
+	// is asked to send it.
+	checkQuota func(size int64) error
+
 	err error
 }
 
@@ -144,7 +171,11 @@ func (p *Parser) Expect(s string) {
 }
 
 func (p *Parser) ReadListMailbox() string {
-	return p.ReadStringWithExtra(map[byte]bool{'%': true, '*': true})
+	name := p.ReadStringWithExtra(map[byte]bool{'%': true, '*': true})
+	if !p.Valid() {
+		return ""
+	}
+	return p.decodeMailbox(name)
 }
 
 func (p *Parser) ReadSpace() {
@@ -283,7 +314,7 @@ func (p *Parser) ReadLiteralPrefix() (size int, sync bool) {
 	p.Expect("{")
 	size = p.ReadInt()
 
-	// RFC 2088 (LITERAL+): http://tools.ietf.org/html/rfc2088
+	// RFC 7888 (LITERAL+/LITERAL-): https://tools.ietf.org/html/rfc7888
 	if p.accept("+") {
 		sync = false
 	} else {
@@ -291,6 +322,19 @@ func (p *Parser) ReadLiteralPrefix() (size int, sync bool) {
 	}
 
 	p.Expect("}\r\n")
+	if !p.Valid() {
+		return
+	}
+
+	if p.literalMinus && !sync && size > maxLiteralMinus {
+		p.rejectLiteral(size, sync, errLiteralMinus)
+		return
+	}
+	if p.literalFilter != nil {
+		if err := p.literalFilter(p.command, size, sync); err != nil {
+			p.rejectLiteral(size, sync, err)
+		}
+	}
 	return
 }
 
@@ -314,12 +358,44 @@ func (p *Parser) ReadLiteralStream() io.Reader {
 		return nil
 	}
 
+	if p.literalBudget > 0 {
+		if p.literalUsed+size > p.literalBudget {
+			p.rejectLiteral(size, sync, errLiteralBudget)
+			return nil
+		}
+		p.literalUsed += size
+	}
+
+	return p.streamLiteral(size, sync)
+}
+
+func (p *Parser) streamLiteral(size int, sync bool) io.Reader {
 	if sync {
 		p.w.Continuation("ready")
 	}
 
 	p.isEOL = true
-	return io.LimitReader(p.r, int64(size))
+	p.literalStream = &io.LimitedReader{R: p.r, N: int64(size)}
+	return p.literalStream
+}
+
+// rejectLiteral fails the command because of a literal. A synchronizing
+// literal is refused before the client sends it; a non-synchronizing one
+// is on its way regardless, and gets discarded.
+func (p *Parser) rejectLiteral(size int, sync bool, err error) {
+	if sync {
+		p.literalRefused = true
+	} else {
+		p.skipLiteral(size)
+	}
+	p.err = err
+}
+
+// skipLiteral arranges for a literal the client is sending anyway (i.e. a
+// non-synchronizing one) to be discarded.
+func (p *Parser) skipLiteral(size int) {
+	p.isEOL = true
+	p.literalStream = &io.LimitedReader{R: p.r, N: int64(size)}
 }
 
 func (p *Parser) advance(n int) {
@@ -422,6 +498,13 @@ Parse:
 
 	buf.Write([]byte(data[lastEscape : n-1]))
 
+	// RFC 6855: with UTF8=ACCEPT, quoted strings may contain UTF-8, but it
+	// has to be valid
+	if p.utf8Accept && !utf8.Valid(buf.Bytes()) {
+		p.err = ProtocolErrorf("invalid UTF-8 in quoted string near %q", data)
+		return ""
+	}
+
 	p.advance(n)
 	return buf.String()
 }
@@ -458,18 +541,73 @@ func (p *Parser) ReadEOL() {
 }
 
 func (p *Parser) readLine() {
+	if p.literalStream != nil {
+		io.Copy(ioutil.Discard, p.literalStream)
+		p.literalStream = nil
+	}
+
 	p.pos = 0
+	p.literalRefused = false
 	p.line, p.err = p.r.ReadString('\n')
 	if len(p.line) > 0 {
 		p.isEOL = false
 	}
 }
 
+// DiscardLine skips the rest of the current command, so that the next read
+// starts at a new command. A command continues past the end of a line when
+// the line ends in a literal; the client sends non-synchronizing literals
+// regardless, so they (and the lines after them) are skipped too. A
+// synchronizing literal that was never acknowledged ends the command, as
+// the client is still waiting for a continuation.
 func (p *Parser) DiscardLine() {
+	p.err = nil
+	for {
+		if p.literalStream != nil {
+			p.readLine() // drains the literal and reads what follows it
+			if p.err != nil {
+				break
+			}
+			continue
+		}
+		if p.literalRefused {
+			break
+		}
+
+		size, sync, ok := trailingLiteral(p.line)
+		if !ok || sync {
+			break
+		}
+		p.skipLiteral(size)
+	}
+
 	p.err = nil
 	p.isEOL = true
 }
 
+// trailingLiteral parses the literal prefix (`{n}` or `{n+}`) at the end
+// of a line, if there is one.
+func trailingLiteral(line string) (size int, sync bool, ok bool) {
+	if !strings.HasSuffix(line, "}\r\n") {
+		return 0, false, false
+	}
+	line = line[:len(line)-3]
+
+	start := strings.LastIndexByte(line, '{')
+	if start < 0 {
+		return 0, false, false
+	}
+	digits := line[start+1:]
+	sync = !strings.HasSuffix(digits, "+")
+	digits = strings.TrimSuffix(digits, "+")
+
+	size, err := strconv.Atoi(digits)
+	if err != nil || size < 0 {
+		return 0, false, false
+	}
+	return size, sync, true
+}
+
 func (p *Parser) String() string {
 	return p.line
 }
@@ -530,6 +668,16 @@ func (p *Parser) ReadList(tokenReader func() string) []string {
 }
 
 func (p *Parser) ReadSequenceSet() *SequenceSet {
+	// RFC 5182 (SEARCHRES): `$` refers to the saved search result, and
+	// can't be combined with anything else
+	if p.accept("$") {
+		if tail := p.Tail(); tail != "" && !strings.ContainsRune(" )\r", rune(tail[0])) {
+			p.err = ProtocolError("$ must stand alone")
+			return &SequenceSet{}
+		}
+		return &SequenceSet{saved: true}
+	}
+
 	set := &SequenceSet{}
 
 	for {
@@ -615,7 +763,7 @@ func (p *Parser) ReadFetchAttributes() []FetchAttribute {
 		fields = []string{"FLAGS", "INTERNALDATE", "RFC822.SIZE", "ENVELOPE", "BODY"}
 	default:
 		// Handle single-field case
-		f, err := parseFetchAttribute(macro)
+		f, err := p.fetchAttribute(macro)
 		if err != nil {
 			p.err = err
 			return nil
@@ -626,7 +774,7 @@ func (p *Parser) ReadFetchAttributes() []FetchAttribute {
 
 	list := make([]FetchAttribute, 0, len(fields))
 	for _, f := range fields {
-		fa, err := parseFetchAttribute(f)
+		fa, err := p.fetchAttribute(f)
 		if err != nil {
 			p.err = err
 			return nil
@@ -669,20 +817,30 @@ func (p *Parser) ReadFetchAttribute() FetchAttribute {
 		return p.ReadBodyAttribute()
 	}
 
-	fa, err := parseFetchAttribute(field)
+	fa, err := p.fetchAttribute(field)
 	if err != nil {
 		p.err = err
 	}
 	return fa
 }
 
+// fetchAttribute looks up a fetch attribute, taking enabled extensions into
+// account.
+func (p *Parser) fetchAttribute(field string) (FetchAttribute, error) {
+	fa, err := parseFetchAttribute(field)
+	if err == nil && p.utf8Accept && fa.Name() == "ENVELOPE" {
+		fa = UTF8EnvelopeFetchAttribute
+	}
+	return fa, err
+}
+
 func (p *Parser) ReadBodyAttribute() *BodyFetchAttribute {
 	peek := p.readBodyAttributeStart()
 	if !p.Valid() {
 		return nil
 	}
 
-	fa := &BodyFetchAttribute{peek: peek}
+	fa := &BodyFetchAttribute{peek: peek || p.readOnly}
 
 	num := false
 	for {
@@ -805,41 +963,82 @@ func (p *Parser) ReadFlagList() []Flag {
 
 	list := make([]Flag, 0)
 	for _, s := range strs {
-		if isKnownFlag[s] {
-			list = append(list, Flag(s))
-		} else {
-			p.err = ProtocolErrorf("unknown flag %q", s)
+		f, err := parseFlag(s)
+		if err != nil {
+			p.err = err
 			return list
 		}
+		list = append(list, f)
 	}
 
 	return list
 }
 
+// parseFlag validates a flag read from the client. System flags must be
+// ones defined by RFC 3501, other than \Recent, which only the server sets
+// (RFC 3501 §2.3.2); anything else is a keyword and only needs to be a valid
+// atom.
+func parseFlag(s string) (Flag, error) {
+	f := NormalizeFlag(Flag(s))
+	if f.IsSystem() {
+		if !isKnownFlag[f.String()] || f == FlagWildcard {
+			return "", ProtocolErrorf("unknown flag %q", s)
+		}
+		if f == FlagRecent {
+			return "", ProtocolErrorf("flag %q can't be set by clients", s)
+		}
+		return f, nil
+	}
+
+	if !isAtom(s) {
+		return "", ProtocolErrorf("invalid keyword %q", s)
+	}
+	return f, nil
+}
+
+func isAtom(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= char || atomSpecials[c] {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *Parser) ReadSearch() (charset string, query Term) {
-	charset = "us-ascii"
 	p.ReadSpace()
+	return p.readSearchCriteria()
+}
+
+func (p *Parser) readSearchCriteria() (charset string, query Term) {
+	charset = "us-ascii"
 	if p.accept("CHARSET") {
 		p.ReadSpace()
 		charset = p.ReadString()
 		p.ReadSpace()
 	}
 
+	return charset, p.readSearchKeys()
+}
+
+// readSearchKeys reads one or more space-separated search keys, which are
+// implicitly ANDed together.
+func (p *Parser) readSearchKeys() Term {
 	terms := []Term{p.ReadSearchKey()}
 	for p.accept(" ") {
 		terms = append(terms, p.ReadSearchKey())
 	}
 
 	if len(terms) == 1 {
-		query = terms[0]
-	} else {
-		query = &BooleanTerm{
-			Op:    OpAnd,
-			Terms: terms,
-		}
+		return terms[0]
+	}
+	return &BooleanTerm{
+		Op:    OpAnd,
+		Terms: terms,
 	}
-
-	return charset, query
 }
 
 // Adapted from: https://code.google.com/p/go-imap/source/browse/go1/imap/reader.go
@@ -0,0 +1,117 @@
+package imap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QUOTA extension (RFC 9208). Servers that let clients change limits
+// should also register QUOTASET.
+
+// ExtQuota advertises QUOTA along with the resources the server enforces.
+func ExtQuota(resources ...QuotaResource) *Extension {
+	ext := newExtension(CapAuthenticated, "QUOTA")
+	for _, res := range resources {
+		ext.caps = append(ext.caps, capability{"QUOTA=RES-" + string(res), CapAuthenticated})
+	}
+	return ext
+}
+
+type QuotaResource string
+
+const (
+	ResourceStorage QuotaResource = "STORAGE" // in units of 1024 octets
+	ResourceMessage               = "MESSAGE"
+	ResourceMailbox               = "MAILBOX"
+)
+
+// ErrOverQuota is returned by backends when an APPEND, COPY or MOVE would
+// exceed a quota.
+var ErrOverQuota = NewResponseError(CodeOverQuota, "quota exceeded")
+
+// QuotaLimit is the usage and limit of one resource under a quota root.
+// SETQUOTA only sets Limit.
+type QuotaLimit struct {
+	Resource QuotaResource
+	Usage    int64
+	Limit    int64
+}
+
+// MailboxQuota is implemented by mailboxes subject to a quota. Conn.Append
+// calls CheckQuota with each message's size before the client sends it, so
+// a message over quota is refused without being read.
+type MailboxQuota interface {
+	Mailbox
+
+	// CheckQuota returns ErrOverQuota if a message of size octets can't be
+	// added.
+	CheckQuota(size int64) error
+}
+
+// ReadGetQuota reads the argument of `GETQUOTA root`.
+func (p *Parser) ReadGetQuota() (root string) {
+	p.ReadSpace()
+	return p.ReadAstring()
+}
+
+// ReadGetQuotaRoot reads the argument of `GETQUOTAROOT mailbox`.
+func (p *Parser) ReadGetQuotaRoot() (mailbox string) {
+	p.ReadSpace()
+	return p.ReadMailbox()
+}
+
+// ReadSetQuota reads the arguments of `SETQUOTA root (resource limit ...)`.
+func (p *Parser) ReadSetQuota() (root string, limits []QuotaLimit) {
+	p.ReadSpace()
+	root = p.ReadAstring()
+	p.ReadSpace()
+	p.ReadListStart()
+	if !p.Valid() {
+		return "", nil
+	}
+
+	limits = []QuotaLimit{}
+	if p.accept(")") {
+		p.listDepth--
+		return root, limits
+	}
+
+	for {
+		resource := QuotaResource(strings.ToUpper(p.ReadAtom()))
+		p.ReadSpace()
+		limit := p.ReadInt()
+		if !p.Valid() {
+			return "", nil
+		}
+		limits = append(limits, QuotaLimit{Resource: resource, Limit: int64(limit)})
+
+		if !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+	if !p.Valid() {
+		return "", nil
+	}
+	return root, limits
+}
+
+// WriteQuota sends a QUOTA response for a quota root.
+func (c *Conn) WriteQuota(root string, limits []QuotaLimit) {
+	list := make([]string, len(limits))
+	for i, l := range limits {
+		list[i] = fmt.Sprintf("%s %d %d", l.Resource, l.Usage, l.Limit)
+	}
+	c.Splat(fmt.Sprintf("QUOTA %s (%s)", quoteString(root), strings.Join(list, " ")))
+}
+
+// WriteQuotaRoot sends the QUOTAROOT response, listing the quota roots a
+// mailbox belongs to.
+func (c *Conn) WriteQuotaRoot(mailbox string, roots []string) {
+	s := "QUOTAROOT " + quoteString(c.encodeMailbox(mailbox))
+	for _, root := range roots {
+		s += " " + quoteString(root)
+	}
+	c.Splat(s)
+}
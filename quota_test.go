@@ -0,0 +1,66 @@
+package imap_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+type quotaMailbox struct {
+	remaining int64
+}
+
+func (m *quotaMailbox) Name() string     { return "INBOX" }
+func (m *quotaMailbox) UIDValidity() int { return 1 }
+
+func (m *quotaMailbox) Append(flags []imap.Flag, date time.Time, body io.Reader) (int, error) {
+	return 1, nil
+}
+
+func (m *quotaMailbox) CheckQuota(size int64) error {
+	if size > m.remaining {
+		return imap.ErrOverQuota
+	}
+	return nil
+}
+
+func TestReadSetQuota(t *testing.T) {
+	root, limits := NewParser(` "" (storage 512 MESSAGE 1000)`).ReadSetQuota()
+
+	assert.Equal(t, "", root)
+	assert.Equal(t, []imap.QuotaLimit{
+		{Resource: imap.ResourceStorage, Limit: 512},
+		{Resource: imap.ResourceMessage, Limit: 1000},
+	}, limits)
+}
+
+func TestWriteQuota(t *testing.T) {
+	conn, out := NewTestConn("")
+	conn.WriteQuotaRoot("INBOX", []string{""})
+	conn.WriteQuota("", []imap.QuotaLimit{{Resource: imap.ResourceStorage, Usage: 10, Limit: 512}})
+
+	assert.Equal(t, "* QUOTAROOT \"INBOX\" \"\"\r\n"+
+		"* QUOTA \"\" (STORAGE 10 512)\r\n", out.String())
+}
+
+func TestAppendOverQuota(t *testing.T) {
+	conn, out := NewTestConn("A3 APPEND INBOX {310}")
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+
+	err := conn.Append(req, &quotaMailbox{remaining: 100})
+	conn.No(req, err)
+
+	// refused without a continuation request
+	assert.Equal(t, "A3 NO [OVERQUOTA] quota exceeded\r\n", out.String())
+}
+
+func TestQuotaCapabilities(t *testing.T) {
+	r := imap.NewCapabilityRegistry()
+	r.Enable(imap.ExtQuota(imap.ResourceStorage, imap.ResourceMessage))
+	assert.Equal(t, []string{"IMAP4rev1", "QUOTA", "QUOTA=RES-STORAGE", "QUOTA=RES-MESSAGE"}, r.List(true, false))
+	assert.Equal(t, []string{"IMAP4rev1"}, r.List(false, false))
+}
@@ -8,6 +8,18 @@ type Request struct {
 	*Parser
 	Tag     string
 	Command string
+
+	// UID is set for UID-prefixed commands (e.g. `UID FETCH`), in which case
+	// Command holds the command that follows the prefix.
+	UID bool
+}
+
+// Name returns the full command name, including any UID prefix.
+func (r *Request) Name() string {
+	if r.UID {
+		return "UID " + r.Command
+	}
+	return r.Command
 }
 
 func (c *Conn) ReadRequest() (*Request, error) {
@@ -24,6 +36,23 @@ func (c *Conn) ReadRequest() (*Request, error) {
 
 	req.Tag = tag
 	req.Command = strings.ToUpper(cmd)
+	p.command = req.Command
+	p.uid = false
+	p.condStore = false
+	p.literalUsed = 0
+
+	if req.Command == "UID" {
+		req.ReadSpace()
+		cmd = req.ReadAtom()
+		if !p.Valid() {
+			return nil, p.Err()
+		}
+
+		req.UID = true
+		req.Command = strings.ToUpper(cmd)
+		p.command = req.Command
+		p.uid = true
+	}
 
 	return req, nil
 }
@@ -0,0 +1,119 @@
+package imap
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ResponseCode is the bracketed part of a status response, e.g.
+// `[UIDVALIDITY 3857529045]`. Args are already in wire format.
+type ResponseCode struct {
+	Name string
+	Args []string
+}
+
+func (rc ResponseCode) IsZero() bool {
+	return rc.Name == ""
+}
+
+func (rc ResponseCode) String() string {
+	if len(rc.Args) == 0 {
+		return rc.Name
+	}
+	return rc.Name + " " + strings.Join(rc.Args, " ")
+}
+
+// RFC 3501 §7.1
+var (
+	CodeAlert     = ResponseCode{Name: "ALERT"}
+	CodeParse     = ResponseCode{Name: "PARSE"}
+	CodeReadOnly  = ResponseCode{Name: "READ-ONLY"}
+	CodeReadWrite = ResponseCode{Name: "READ-WRITE"}
+	CodeTryCreate = ResponseCode{Name: "TRYCREATE"}
+)
+
+// RFC 5530
+var (
+	CodeUnavailable          = ResponseCode{Name: "UNAVAILABLE"}
+	CodeAuthenticationFailed = ResponseCode{Name: "AUTHENTICATIONFAILED"}
+	CodeAuthorizationFailed  = ResponseCode{Name: "AUTHORIZATIONFAILED"}
+	CodeExpired              = ResponseCode{Name: "EXPIRED"}
+	CodePrivacyRequired      = ResponseCode{Name: "PRIVACYREQUIRED"}
+	CodeContactAdmin         = ResponseCode{Name: "CONTACTADMIN"}
+	CodeNoPerm               = ResponseCode{Name: "NOPERM"}
+	CodeInUse                = ResponseCode{Name: "INUSE"}
+	CodeExpungeIssued        = ResponseCode{Name: "EXPUNGEISSUED"}
+	CodeCorruption           = ResponseCode{Name: "CORRUPTION"}
+	CodeServerBug            = ResponseCode{Name: "SERVERBUG"}
+	CodeClientBug            = ResponseCode{Name: "CLIENTBUG"}
+	CodeCannot               = ResponseCode{Name: "CANNOT"}
+	CodeLimit                = ResponseCode{Name: "LIMIT"}
+	CodeOverQuota            = ResponseCode{Name: "OVERQUOTA"}
+	CodeAlreadyExists        = ResponseCode{Name: "ALREADYEXISTS"}
+	CodeNonExistent          = ResponseCode{Name: "NONEXISTENT"}
+)
+
+func CodeBadCharset(charsets ...string) ResponseCode {
+	if len(charsets) == 0 {
+		return ResponseCode{Name: "BADCHARSET"}
+	}
+	list := make([]string, len(charsets))
+	for i, cs := range charsets {
+		list[i] = quoteString(cs)
+	}
+	return ResponseCode{
+		Name: "BADCHARSET",
+		Args: []string{"(" + strings.Join(list, " ") + ")"},
+	}
+}
+
+func CodeCapability(caps ...string) ResponseCode {
+	return ResponseCode{Name: "CAPABILITY", Args: caps}
+}
+
+func CodePermanentFlags(flags []Flag) ResponseCode {
+	return ResponseCode{
+		Name: "PERMANENTFLAGS",
+		Args: []string{marshalFlagList(flags)},
+	}
+}
+
+func CodeUIDNext(uid int) ResponseCode {
+	return ResponseCode{Name: "UIDNEXT", Args: []string{strconv.Itoa(uid)}}
+}
+
+func CodeUIDValidity(uidValidity int) ResponseCode {
+	return ResponseCode{Name: "UIDVALIDITY", Args: []string{strconv.Itoa(uidValidity)}}
+}
+
+func CodeUnseen(seq int) ResponseCode {
+	return ResponseCode{Name: "UNSEEN", Args: []string{strconv.Itoa(seq)}}
+}
+
+// ResponseError is an error that carries a response code. Backends return it
+// to have the server reply with e.g. `NO [TRYCREATE] no such mailbox`.
+type ResponseError struct {
+	Code ResponseCode
+	Text string
+
+	// Bad makes the server reply BAD rather than NO.
+	Bad bool
+}
+
+func (e *ResponseError) Error() string {
+	return e.Text
+}
+
+func NewResponseError(code ResponseCode, text string) *ResponseError {
+	return &ResponseError{Code: code, Text: text}
+}
+
+// responseCodeOf returns the code carried by err, if any.
+func responseCodeOf(err error) ResponseCode {
+	var re *ResponseError
+	if errors.As(err, &re) {
+		return re.Code
+	}
+	return ResponseCode{}
+}
@@ -0,0 +1,52 @@
+package imap_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCodeString(t *testing.T) {
+	cases := []struct {
+		code imap.ResponseCode
+		want string
+	}{
+		{imap.CodeTryCreate, "TRYCREATE"},
+		{imap.CodeUIDValidity(3857529045), "UIDVALIDITY 3857529045"},
+		{imap.CodeBadCharset(), "BADCHARSET"},
+		{imap.CodeBadCharset("UTF-8", "US-ASCII"), `BADCHARSET ("UTF-8" "US-ASCII")`},
+		{imap.CodeCapability("IMAP4rev1", "LITERAL+"), "CAPABILITY IMAP4rev1 LITERAL+"},
+		{imap.CodePermanentFlags([]imap.Flag{imap.FlagSeen, imap.FlagWildcard}), `PERMANENTFLAGS (\Seen \*)`},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.code.String())
+	}
+	assert.True(t, imap.ResponseCode{}.IsZero())
+}
+
+func TestNoWithWrappedResponseError(t *testing.T) {
+	conn, out := NewTestConn("")
+	r := &imap.Request{Tag: "a1", Command: "CREATE"}
+
+	err := fmt.Errorf("create: %w", imap.NewResponseError(imap.CodeAlreadyExists, "mailbox exists"))
+	conn.No(r, err)
+	assert.Equal(t, "a1 NO [ALREADYEXISTS] create: mailbox exists\r\n", out.String())
+
+	out.Reset()
+	conn.No(r, errors.New("plain failure"))
+	assert.Equal(t, "a1 NO plain failure\r\n", out.String())
+}
+
+func TestWithCode(t *testing.T) {
+	conn, out := NewTestConn("")
+	r := &imap.Request{Tag: "a1", Command: "SEARCH"}
+
+	conn.BadWithCode(r, imap.CodeBadCharset("UTF-8"), errors.New("unsupported charset"))
+	conn.NoWithCode(nil, imap.CodeAlert, errors.New("system going down"))
+	assert.Equal(t, "a1 BAD [BADCHARSET (\"UTF-8\")] unsupported charset\r\n"+
+		"* NO [ALERT] system going down\r\n", out.String())
+}
@@ -44,6 +44,17 @@ type FlagTerm struct {
 
 func (t *FlagTerm) Accept(v TermVisitor) { v.VisitFlagTerm(t) }
 
+// Matches reports whether a message with the given flags satisfies the term.
+// Flags are compared case-insensitively.
+func (t *FlagTerm) Matches(flags []Flag) bool {
+	for _, f := range flags {
+		if f.Equal(t.Flag) {
+			return t.Present
+		}
+	}
+	return !t.Present
+}
+
 type StringTerm struct {
 	Op     Op
 	Field  Field
@@ -111,6 +122,7 @@ const (
 	SizeField         = "Size"
 	DateField         = "Date"
 	InternalDateField = "InternalDate"
+	ModSeqField       = "ModSeq"
 )
 
 // Deviates from RFC3501 somewhat:
@@ -173,9 +185,26 @@ func (p *Parser) ReadSearchKey() Term {
 		p.ReadSpace()
 		a := p.ReadAtom()
 		return &FlagTerm{
-			Flag:    Flag(a),
+			Flag:    NormalizeFlag(Flag(a)),
 			Present: true,
 		}
+	} else if p.accept("modseq") {
+		// RFC 7162: MODSEQ [<entry-name> <entry-type-req>] <mod-sequence>
+		// Per-flag metadata entries aren't tracked separately, so they're
+		// read and ignored.
+		p.ReadSpace()
+		if p.Peek() == '"' {
+			p.ReadQuotedString()
+			p.ReadSpace()
+			p.ReadAtom()
+			p.ReadSpace()
+		}
+		m := p.ReadModSeq()
+		return &IntTerm{
+			Op:    OpGTE,
+			Field: ModSeqField,
+			Int:   int(m),
+		}
 	} else if p.accept("new") {
 		return &BooleanTerm{
 			Op: OpAnd,
@@ -239,7 +268,7 @@ func (p *Parser) ReadSearchKey() Term {
 	} else if p.accept("unkeyword") {
 		p.ReadSpace()
 		a := p.ReadAtom()
-		return &FlagTerm{Flag: Flag(a), Present: false}
+		return &FlagTerm{Flag: NormalizeFlag(Flag(a)), Present: false}
 	} else if p.accept("unseen") {
 		return &FlagTerm{Flag: FlagSeen, Present: false}
 	} else if p.accept("draft") {
@@ -0,0 +1,112 @@
+package imap
+
+import (
+	"fmt"
+)
+
+// MailboxStatus describes the state of a mailbox being selected. Its name,
+// UIDVALIDITY and flags come from the Mailbox itself.
+type MailboxStatus struct {
+	Messages int
+	Recent   int
+	Unseen   int // sequence number of the first unseen message, or 0
+	UIDNext  int
+
+	// HighestModSeq is zero if the mailbox doesn't support persistent
+	// mod-sequences.
+	HighestModSeq uint64
+
+	ReadOnly bool
+}
+
+// WriteExists sends an EXISTS response.
+func (c *Conn) WriteExists(n int) {
+	c.Splat(fmt.Sprintf("%d EXISTS", n))
+}
+
+// WriteRecent sends a RECENT response.
+func (c *Conn) WriteRecent(n int) {
+	c.Splat(fmt.Sprintf("%d RECENT", n))
+}
+
+// Select sends the responses for a successful SELECT or EXAMINE, in the
+// order RFC 3501 gives them, and completes the request. EXAMINE always
+// opens the mailbox read-only. While a mailbox is open read-only, fetching
+// a body doesn't set \Seen. With QRESYNC enabled, closing a previously
+// selected mailbox is reported with `OK [CLOSED]` (RFC 7162 §3.2.11). The
+// CONDSTORE select parameter enables CONDSTORE. A user without the "r"
+// right is refused, which closes any mailbox already selected.
+func (c *Conn) Select(r *Request, mbox Mailbox, status *MailboxStatus) error {
+	if err := c.Authorize(mbox.Name(), CommandRights(r.Command)); err != nil {
+		c.Unselect()
+		return err
+	}
+	readOnly := status.ReadOnly || r.Command == "EXAMINE"
+
+	if c.Selected() != "" && c.Enabled("QRESYNC") {
+		c.SplatOk(CodeClosed, "Previous mailbox is now closed")
+	}
+
+	flags, perm := mailboxFlags(mbox)
+	if readOnly {
+		perm = []Flag{}
+	}
+
+	c.WriteFlags(flags)
+	c.WriteExists(status.Messages)
+	c.WriteRecent(status.Recent)
+	if status.Unseen > 0 {
+		c.SplatOk(CodeUnseen(status.Unseen), fmt.Sprintf("Message %d is first unseen", status.Unseen))
+	}
+	c.writePermanentFlags(perm)
+	c.SplatOk(CodeUIDValidity(mbox.UIDValidity()), "UIDs valid")
+	c.SplatOk(CodeUIDNext(status.UIDNext), "Predicted next UID")
+	if c.parser.condStore {
+		c.enable("CONDSTORE")
+	}
+	if status.HighestModSeq > 0 {
+		c.SplatOk(CodeHighestModSeq(status.HighestModSeq), "Highest")
+	} else if c.Enabled("CONDSTORE") {
+		c.SplatOk(CodeNoModSeq, "Sorry, this mailbox format doesn't support modsequences")
+	}
+
+	c.SetState(StateSelected)
+	c.mu.Lock()
+	c.selected = mbox.Name()
+	c.searchResult = nil // RFC 5182 §2.1
+	c.mu.Unlock()
+	c.parser.readOnly = readOnly
+
+	if readOnly {
+		c.OkWithCode(r, CodeReadOnly)
+	} else {
+		c.OkWithCode(r, CodeReadWrite)
+	}
+	return nil
+}
+
+// Unselect returns to the authenticated state after CLOSE, UNSELECT or a
+// failed SELECT or EXAMINE, which also closes the previous mailbox
+// (RFC 3501 §6.3.1). It forgets the saved search result.
+func (c *Conn) Unselect() {
+	c.mu.Lock()
+	if c.state == StateSelected {
+		c.state = StateAuthenticated
+	}
+	c.selected = ""
+	c.searchResult = nil
+	c.mu.Unlock()
+	c.parser.readOnly = false
+}
+
+// Selected returns the name of the selected mailbox.
+func (c *Conn) Selected() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.selected
+}
+
+// ReadOnly reports whether the selected mailbox was opened read-only.
+func (c *Conn) ReadOnly() bool {
+	return c.parser.readOnly
+}
@@ -0,0 +1,124 @@
+package imap_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	conn, out := NewTestConn("A142 SELECT INBOX")
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	mbox := flaggedMailbox{
+		namedMailbox: "INBOX",
+		flags:        []imap.Flag{imap.FlagAnswered, imap.FlagSeen},
+		perm:         []imap.Flag{imap.FlagSeen, imap.FlagWildcard},
+	}
+	conn.Select(req, mbox, &imap.MailboxStatus{
+		Messages: 172,
+		Recent:   1,
+		Unseen:   12,
+		UIDNext:  4392,
+	})
+
+	assert.Equal(t, "* FLAGS (\\Answered \\Seen)\r\n"+
+		"* 172 EXISTS\r\n"+
+		"* 1 RECENT\r\n"+
+		"* OK [UNSEEN 12] Message 12 is first unseen\r\n"+
+		"* OK [PERMANENTFLAGS (\\Seen \\*)] Limited\r\n"+
+		"* OK [UIDVALIDITY 3857529045] UIDs valid\r\n"+
+		"* OK [UIDNEXT 4392] Predicted next UID\r\n"+
+		"A142 OK [READ-WRITE] SELECT completed\r\n", out.String())
+	assert.Equal(t, imap.StateSelected, conn.State())
+	assert.False(t, conn.ReadOnly())
+}
+
+func TestExamineDisablesImplicitSeen(t *testing.T) {
+	conn, out := NewTestConn("A932 EXAMINE blurdybloop\r\nA933 FETCH 1 BODY[TEXT]")
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.DiscardLine()
+
+	conn.Select(req, namedMailbox("blurdybloop"), &imap.MailboxStatus{Messages: 17, UIDNext: 4392})
+	assert.True(t, strings.Contains(out.String(), "* OK [PERMANENTFLAGS ()] No permanent flags permitted\r\n"))
+	assert.True(t, strings.Contains(out.String(), "A932 OK [READ-ONLY] EXAMINE completed\r\n"))
+	assert.True(t, conn.ReadOnly())
+
+	req, err = conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.ReadSpace()
+	req.ReadSequenceSet()
+	req.ReadSpace()
+	fa := req.ReadBodyAttribute()
+	if assert.NotNil(t, fa) {
+		assert.False(t, fa.SetsSeen())
+	}
+}
+
+func TestUnselect(t *testing.T) {
+	conn, _ := NewTestConn("a1 EXAMINE INBOX")
+	conn.SetState(imap.StateAuthenticated)
+	req, _ := conn.ReadRequest()
+	req.DiscardLine()
+	conn.Select(req, namedMailbox("INBOX"), &imap.MailboxStatus{})
+	conn.SaveSearchResult(imap.NewSequenceSet([]int{1}))
+
+	conn.Unselect()
+	assert.Equal(t, imap.StateAuthenticated, conn.State())
+	assert.Equal(t, "", conn.Selected())
+	assert.False(t, conn.ReadOnly())
+
+	marker := NewParser(" $")
+	marker.ReadSpace()
+	set, _ := conn.ResolveSequenceSet(req, marker.ReadSequenceSet())
+	assert.Equal(t, 0, set.Len())
+}
+
+func TestSelectDefaultFlags(t *testing.T) {
+	conn, out := NewTestConn("a1 SELECT INBOX")
+	req, _ := conn.ReadRequest()
+	req.DiscardLine()
+	conn.Select(req, namedMailbox("INBOX"), &imap.MailboxStatus{})
+
+	other, flags := NewTestConn("")
+	other.WriteMailboxFlags(namedMailbox("INBOX"))
+	for _, line := range strings.SplitAfter(flags.String(), "\r\n") {
+		assert.True(t, strings.Contains(out.String(), line), line)
+	}
+}
+
+func TestSelectReportsClosed(t *testing.T) {
+	for _, qresync := range []bool{false, true} {
+		conn, out := NewTestConn("a1 ENABLE QRESYNC\r\na2 SELECT INBOX\r\na3 SELECT Drafts")
+		conn.SetState(imap.StateAuthenticated)
+		conn.Capabilities().Enable(imap.ExtQResync)
+
+		req, _ := conn.ReadRequest()
+		caps := req.ReadEnable()
+		req.ReadEOL()
+		if qresync {
+			conn.Enable(req, caps)
+		}
+
+		req, _ = conn.ReadRequest()
+		req.DiscardLine()
+		conn.Select(req, namedMailbox("INBOX"), &imap.MailboxStatus{})
+		assert.False(t, strings.Contains(out.String(), "[CLOSED]"))
+
+		out.Reset()
+		req, _ = conn.ReadRequest()
+		req.DiscardLine()
+		conn.Select(req, namedMailbox("Drafts"), &imap.MailboxStatus{})
+		assert.Equal(t, qresync, strings.HasPrefix(out.String(), "* OK [CLOSED] "), out.String())
+	}
+}
@@ -13,6 +13,7 @@ type SequenceRange [2]int
 
 type SequenceSet struct {
 	ranges []SequenceRange
+	saved  bool
 }
 
 func (r *SequenceRange) String() string {
@@ -24,6 +25,10 @@ func (r *SequenceRange) String() string {
 }
 
 func (s *SequenceSet) String() string {
+	if s.saved {
+		return "$"
+	}
+
 	strs := make([]string, len(s.ranges))
 	for i, r := range s.ranges {
 		strs[i] = r.String()
@@ -67,6 +72,31 @@ func NewSequenceSetWithRange(rng SequenceRange) *SequenceSet {
 	}
 }
 
+// NewSequenceSet builds a compact set from a list of numbers, merging runs of
+// consecutive ascending numbers into ranges. Order is preserved, which
+// matters for responses like COPYUID where two sets correspond pairwise.
+func NewSequenceSet(nums []int) *SequenceSet {
+	s := &SequenceSet{}
+	for _, n := range nums {
+		if l := len(s.ranges); l > 0 {
+			last := &s.ranges[l-1]
+			if last[0] <= last[1] && last[1]+1 == n {
+				last[1] = n
+				continue
+			}
+		}
+		s.Append(SequenceRange{n, n})
+	}
+	return s
+}
+
+// IsSaved reports whether the set is the `$` marker, which refers to the
+// result of the last SEARCH ... RETURN (SAVE). Use Conn.ResolveSequenceSet to
+// get the actual messages.
+func (s *SequenceSet) IsSaved() bool {
+	return s.saved
+}
+
 func (s *SequenceSet) Len() int {
 	l := 0
 	for _, rng := range s.ranges {
@@ -0,0 +1,282 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrServerClosed = errors.New("imap: server closed")
+
+// A Handler executes a single command. It is responsible for reading the
+// rest of the command line and writing the tagged completion response. If it
+// returns an error instead, the server replies on its behalf: BAD for a
+// ProtocolError, NO for anything else. A *ResponseError's code is included
+// in the reply, e.g. `NO [TRYCREATE] no such mailbox`.
+type Handler interface {
+	ServeIMAP(c *Conn, r *Request) error
+}
+
+type HandlerFunc func(c *Conn, r *Request) error
+
+func (f HandlerFunc) ServeIMAP(c *Conn, r *Request) error {
+	return f(c, r)
+}
+
+type Server struct {
+	Handler Handler
+
+	// Greeting is the human-readable text of the initial OK/PREAUTH response.
+	Greeting string
+
+	// Preauth, if set, is consulted for each new connection. Returning true
+	// greets the client with PREAUTH and starts it in the authenticated state.
+	Preauth func(c *Conn) bool
+
+	// IdleTimeout is the autologout timer (RFC 3501 §5.4). Zero disables it.
+	IdleTimeout time.Duration
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	wg        sync.WaitGroup
+	closing   int32
+}
+
+type serverConn struct {
+	*Conn
+	nc net.Conn
+
+	mu   sync.Mutex
+	busy bool
+}
+
+func (s *Server) shuttingDown() bool {
+	return atomic.LoadInt32(&s.closing) != 0
+}
+
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.shuttingDown() {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+	}()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		sc := &serverConn{Conn: NewConn(nc), nc: nc}
+		if !s.track(sc) {
+			sc.Bye("Server shutting down")
+			sc.nc.Close()
+			continue
+		}
+		go s.serve(sc)
+	}
+}
+
+func (s *Server) track(sc *serverConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shuttingDown() {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]struct{})
+	}
+	s.conns[sc] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(sc *serverConn) {
+	s.mu.Lock()
+	delete(s.conns, sc)
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+func (s *Server) serve(sc *serverConn) {
+	defer s.untrack(sc)
+	defer sc.Close()
+
+	greeting := s.Greeting
+	if greeting == "" {
+		greeting = "IMAP4rev1 ready"
+	}
+	if s.Preauth != nil && s.Preauth(sc.Conn) {
+		sc.Preauth(greeting)
+	} else {
+		sc.Greet(greeting)
+	}
+
+	for {
+		if !sc.beginRead(s) {
+			sc.Bye("Server shutting down")
+			return
+		}
+
+		// A command that has been read runs even if Shutdown started in the
+		// meantime; the loop says BYE once it completes.
+		req, err := sc.ReadRequest()
+		if err != nil {
+			if s.shuttingDown() {
+				sc.Bye("Server shutting down")
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				sc.Bye("Autologout; idle for too long")
+				return
+			}
+			if _, ok := err.(ProtocolError); !ok {
+				return // io.EOF or a broken transport
+			}
+			sc.Bad(nil, err)
+			sc.DiscardLine()
+			continue
+		}
+
+		sc.beginCommand()
+		err = sc.authorizeCommand(req)
+		if err == nil {
+			err = s.handle(sc.Conn, req)
+		}
+		if err != nil {
+			s.reply(sc.Conn, req, err)
+			sc.DiscardLine()
+		}
+		sc.endCommand()
+
+		if sc.State() == StateLogout {
+			return
+		}
+	}
+}
+
+func (s *Server) handle(c *Conn, r *Request) error {
+	if s.Handler == nil {
+		return ProtocolErrorf("unknown command %q", r.Command)
+	}
+	return s.Handler.ServeIMAP(c, r)
+}
+
+func (s *Server) reply(c *Conn, r *Request, err error) {
+	if err == io.EOF {
+		return
+	}
+	var re *ResponseError
+	if _, ok := err.(ProtocolError); ok {
+		c.Bad(r, err)
+	} else if errors.As(err, &re) && re.Bad {
+		c.Bad(r, err)
+	} else {
+		c.No(r, err)
+	}
+}
+
+// beginRead arms the idle timer before waiting for the next command. It
+// returns false if the server is shutting down.
+func (sc *serverConn) beginRead(s *Server) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if s.shuttingDown() {
+		return false
+	}
+	if s.IdleTimeout > 0 {
+		sc.nc.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+	} else {
+		sc.nc.SetReadDeadline(time.Time{})
+	}
+	return true
+}
+
+// beginCommand marks the connection busy so that Shutdown lets the command
+// finish, and lifts any deadline an interrupt set while it was being read.
+func (sc *serverConn) beginCommand() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.busy = true
+	sc.nc.SetReadDeadline(time.Time{})
+}
+
+func (sc *serverConn) endCommand() {
+	sc.mu.Lock()
+	sc.busy = false
+	sc.mu.Unlock()
+}
+
+// interrupt wakes an idle connection blocked waiting for a command. Busy
+// connections notice the shutdown once their command completes.
+func (sc *serverConn) interrupt() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if !sc.busy {
+		sc.nc.SetReadDeadline(time.Now())
+	}
+}
+
+// Shutdown stops accepting connections, sends BYE to every live connection
+// and waits for in-flight commands to finish. If ctx expires first, the
+// remaining connections are closed forcibly.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	atomic.StoreInt32(&s.closing, 1)
+	for l := range s.listeners {
+		l.Close()
+	}
+	conns := make([]*serverConn, 0, len(s.conns))
+	for sc := range s.conns {
+		conns = append(conns, sc)
+	}
+	s.mu.Unlock()
+
+	for _, sc := range conns {
+		sc.interrupt()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		// Only the network connection is closed here. Its goroutine
+		// still owns the Conn's transport, which COMPRESS may have
+		// replaced, and closes it on the way out.
+		s.mu.Lock()
+		for sc := range s.conns {
+			sc.nc.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,298 @@
+package imap_test
+
+import (
+	"bufio"
+	"compress/flate"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerShutdownSendsBye(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen:", err)
+	}
+
+	s := &imap.Server{
+		Handler: imap.HandlerFunc(func(c *imap.Conn, r *imap.Request) error {
+			r.ReadEOL()
+			c.Ok(r)
+			return nil
+		}),
+	}
+	go s.Serve(l)
+
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	br := bufio.NewReader(nc)
+
+	greeting, _ := br.ReadString('\n')
+	assert.True(t, strings.HasPrefix(greeting, "* OK [CAPABILITY IMAP4rev1"), greeting)
+
+	nc.Write([]byte("a1 NOOP\r\n"))
+	ok, _ := br.ReadString('\n')
+	assert.Equal(t, "a1 OK NOOP completed\r\n", ok)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx))
+
+	bye, _ := br.ReadString('\n')
+	assert.Equal(t, "* BYE Server shutting down\r\n", bye)
+}
+
+func TestServerRepliesBad(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen:", err)
+	}
+
+	s := &imap.Server{
+		Handler: imap.HandlerFunc(func(c *imap.Conn, r *imap.Request) error {
+			r.DiscardLine()
+			switch r.Command {
+			case "SEARCH":
+				err := &imap.ResponseError{Code: imap.CodeBadCharset("UTF-8"), Text: "unsupported charset", Bad: true}
+				return fmt.Errorf("search: %w", err)
+			case "CREATE":
+				return imap.NewResponseError(imap.CodeAlreadyExists, "mailbox exists")
+			}
+			return imap.ProtocolError("unknown command")
+		}),
+	}
+	go s.Serve(l)
+	defer s.Shutdown(context.Background())
+
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	br := bufio.NewReader(nc)
+	br.ReadString('\n')
+
+	nc.Write([]byte("a1 SEARCH CHARSET X ALL\r\na2 CREATE foo\r\na3 FOO\r\n"))
+	line, _ := br.ReadString('\n')
+	assert.Equal(t, "a1 BAD [BADCHARSET (\"UTF-8\")] search: unsupported charset\r\n", line)
+	line, _ = br.ReadString('\n')
+	assert.Equal(t, "a2 NO [ALREADYEXISTS] mailbox exists\r\n", line)
+	line, _ = br.ReadString('\n')
+	assert.Equal(t, "a3 BAD unknown command\r\n", line)
+}
+
+func TestServerShutdownFinishesCommand(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen:", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := &imap.Server{
+		Handler: imap.HandlerFunc(func(c *imap.Conn, r *imap.Request) error {
+			r.ReadEOL()
+			close(started)
+			<-release
+			c.Ok(r)
+			return nil
+		}),
+	}
+	go s.Serve(l)
+
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	br := bufio.NewReader(nc)
+	br.ReadString('\n')
+
+	nc.Write([]byte("a1 NOOP\r\n"))
+	<-started
+
+	done := make(chan error)
+	go func() { done <- s.Shutdown(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	ok, _ := br.ReadString('\n')
+	assert.Equal(t, "a1 OK NOOP completed\r\n", ok)
+	bye, _ := br.ReadString('\n')
+	assert.Equal(t, "* BYE Server shutting down\r\n", bye)
+	assert.NoError(t, <-done)
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen:", err)
+	}
+
+	s := &imap.Server{IdleTimeout: 50 * time.Millisecond}
+	go s.Serve(l)
+	defer s.Shutdown(context.Background())
+
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	br := bufio.NewReader(nc)
+	br.ReadString('\n')
+
+	bye, _ := br.ReadString('\n')
+	assert.Equal(t, "* BYE Autologout; idle for too long\r\n", bye)
+	_, err = br.ReadString('\n')
+	assert.Error(t, err, "the connection is closed after BYE")
+}
+
+func TestServerEnforcesSelectedRights(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen:", err)
+	}
+
+	s := &imap.Server{
+		Preauth: func(c *imap.Conn) bool {
+			c.SetRights(func(mailbox string) (imap.RightSet, error) {
+				return "lrs", nil
+			})
+			return true
+		},
+		Handler: imap.HandlerFunc(func(c *imap.Conn, r *imap.Request) error {
+			switch r.Command {
+			case "SELECT":
+				r.DiscardLine()
+				return c.Select(r, namedMailbox("Shared"), &imap.MailboxStatus{})
+			case "STORE":
+				cmd := r.ReadStore()
+				if cmd == nil {
+					return r.Err()
+				}
+				if err := c.AuthorizeStore(cmd); err != nil {
+					return err
+				}
+				r.ReadEOL()
+				c.Ok(r)
+			case "CLOSE":
+				r.ReadEOL()
+				if c.ExpungeOnClose() {
+					return errors.New("expunged without rights")
+				}
+				c.Ok(r)
+			default:
+				r.DiscardLine()
+				c.Ok(r)
+			}
+			return nil
+		}),
+	}
+	go s.Serve(l)
+	defer s.Shutdown(context.Background())
+
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	br := bufio.NewReader(nc)
+	br.ReadString('\n')
+
+	nc.Write([]byte("a1 SELECT Shared\r\n"))
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil || strings.HasPrefix(line, "a1 ") {
+			break
+		}
+	}
+
+	nc.Write([]byte("a2 EXPUNGE\r\n"))
+	no, _ := br.ReadString('\n')
+	assert.Equal(t, "a2 NO [NOPERM] permission denied; \"e\" rights required\r\n", no)
+
+	nc.Write([]byte("a3 STORE 1 +FLAGS (\\Deleted)\r\n"))
+	no, _ = br.ReadString('\n')
+	assert.Equal(t, "a3 NO [NOPERM] permission denied; \"t\" rights required\r\n", no)
+
+	nc.Write([]byte("a4 STORE 1 +FLAGS.SILENT (\\Seen)\r\n"))
+	ok, _ := br.ReadString('\n')
+	assert.Equal(t, "a4 OK STORE completed\r\n", ok)
+
+	nc.Write([]byte("a5 CLOSE\r\n"))
+	ok, _ = br.ReadString('\n')
+	assert.Equal(t, "a5 OK CLOSE completed\r\n", ok)
+
+	nc.Write([]byte("a6 CREATE Shared/Sub\r\n"))
+	no, _ = br.ReadString('\n')
+	assert.Equal(t, "a6 NO [NOPERM] permission denied; \"k\" rights required\r\n", no)
+
+	nc.Write([]byte("a7 DELETE \"Shared\"\r\n"))
+	no, _ = br.ReadString('\n')
+	assert.Equal(t, "a7 NO [NOPERM] permission denied; \"x\" rights required\r\n", no)
+
+	nc.Write([]byte("a8 CREATE Top\r\n"))
+	ok, _ = br.ReadString('\n')
+	assert.Equal(t, "a8 OK CREATE completed\r\n", ok)
+}
+
+func TestServerForcedShutdownWithCompress(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen:", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := &imap.Server{
+		Handler: imap.HandlerFunc(func(c *imap.Conn, r *imap.Request) error {
+			if r.Command == "COMPRESS" {
+				r.ReadCompress()
+				r.ReadEOL()
+				return c.Compress(r)
+			}
+			r.ReadEOL()
+			close(started)
+			<-release
+			c.Ok(r)
+			return nil
+		}),
+	}
+	go s.Serve(l)
+
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	br := bufio.NewReader(nc)
+	br.ReadString('\n')
+
+	nc.Write([]byte("a1 COMPRESS DEFLATE\r\n"))
+	ok, _ := br.ReadString('\n')
+	assert.Equal(t, "a1 OK COMPRESS completed\r\n", ok)
+
+	w, _ := flate.NewWriter(nc, flate.DefaultCompression)
+	w.Write([]byte("a2 NOOP\r\n"))
+	w.Flush()
+	<-started
+
+	// The command's reply races with the forced close.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	close(release)
+	s.Shutdown(ctx)
+
+	assert.NoError(t, s.Shutdown(context.Background()), "the connection's goroutine exits")
+}
@@ -0,0 +1,271 @@
+package imap
+
+import (
+	"mime"
+	netmail "net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulrosania/go-mail"
+)
+
+// SORT extension (RFC 5256)
+
+var ExtSort = newExtension(CapAuthenticated, "SORT")
+
+type SortKey string
+
+const (
+	SortArrival SortKey = "ARRIVAL"
+	SortCc              = "CC"
+	SortDate            = "DATE"
+	SortFrom            = "FROM"
+	SortSize            = "SIZE"
+	SortSubject         = "SUBJECT"
+	SortTo              = "TO"
+)
+
+var isSortKey = map[string]bool{
+	string(SortArrival): true,
+	SortCc:              true,
+	SortDate:            true,
+	SortFrom:            true,
+	SortSize:            true,
+	SortSubject:         true,
+	SortTo:              true,
+}
+
+type SortCriterion struct {
+	Key     SortKey
+	Reverse bool
+}
+
+// ReadSort reads the arguments of `SORT (criteria) charset search-keys`.
+func (p *Parser) ReadSort() (criteria []SortCriterion, charset string, query Term) {
+	p.ReadSpace()
+	p.ReadListStart()
+	if !p.Valid() {
+		return
+	}
+
+	for {
+		reverse := p.accept("REVERSE ")
+		key := strings.ToUpper(p.ReadAtom())
+		if !p.Valid() {
+			return nil, "", nil
+		}
+		if !isSortKey[key] {
+			p.err = ProtocolErrorf("unknown sort key %q", key)
+			return nil, "", nil
+		}
+
+		criteria = append(criteria, SortCriterion{Key: SortKey(key), Reverse: reverse})
+		if !p.accept(" ") {
+			break
+		}
+	}
+
+	p.ReadListEnd()
+	p.ReadSpace()
+	charset = p.ReadAstring()
+	p.ReadSpace()
+	if !p.Valid() {
+		return nil, "", nil
+	}
+
+	return criteria, charset, p.readSearchKeys()
+}
+
+// Compare orders two messages by the criterion, returning a negative
+// number, zero or a positive number.
+func (c SortCriterion) Compare(a, b *Message) int {
+	cmp := 0
+	switch c.Key {
+	case SortArrival:
+		cmp = compareTimes(a.ReceivedAt, b.ReceivedAt)
+	case SortCc:
+		cmp = strings.Compare(firstMailbox(a, "Cc"), firstMailbox(b, "Cc"))
+	case SortDate:
+		cmp = compareTimes(SentDate(a), SentDate(b))
+	case SortFrom:
+		cmp = strings.Compare(firstMailbox(a, "From"), firstMailbox(b, "From"))
+	case SortSize:
+		cmp = a.RFC822Size - b.RFC822Size
+	case SortSubject:
+		sa, _ := BaseSubject(a.Header.Get("Subject"))
+		sb, _ := BaseSubject(b.Header.Get("Subject"))
+		cmp = strings.Compare(sa, sb)
+	case SortTo:
+		cmp = strings.Compare(firstMailbox(a, "To"), firstMailbox(b, "To"))
+	}
+
+	if c.Reverse {
+		return -cmp
+	}
+	return cmp
+}
+
+// SortMessages sorts msgs in place. Messages that compare equal under every
+// criterion keep their relative order, so msgs should be passed in sequence
+// number order.
+func SortMessages(msgs []*Message, criteria []SortCriterion) {
+	sort.SliceStable(msgs, func(i, j int) bool {
+		for _, c := range criteria {
+			if cmp := c.Compare(msgs[i], msgs[j]); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+}
+
+// WriteSort sends the SORT response. ids are sequence numbers or UIDs, in
+// sorted order.
+func (c *Conn) WriteSort(ids []int) {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(id)
+	}
+
+	if len(strs) == 0 {
+		c.Splat("SORT")
+	} else {
+		c.Splat("SORT " + strings.Join(strs, " "))
+	}
+}
+
+func compareTimes(a, b time.Time) int {
+	if a.Before(b) {
+		return -1
+	} else if a.After(b) {
+		return 1
+	}
+	return 0
+}
+
+// SentDate returns the message's Date header, falling back to the internal
+// date if it is missing or unparseable (RFC 5256 §2.2).
+func SentDate(m *Message) time.Time {
+	if d := m.Header.Get("Date"); d != "" {
+		if t, err := netmail.ParseDate(d); err == nil {
+			return t
+		}
+	}
+	return m.ReceivedAt
+}
+
+// firstMailbox returns the local-part of the first address in the header,
+// upper-cased for i;ascii-casemap comparison.
+func firstMailbox(m *Message, key string) string {
+	h := m.Header.Get(key)
+	if h == "" {
+		return ""
+	}
+
+	ap := mail.NewAddressParser(h)
+	if len(ap.Addresses) == 0 {
+		return ""
+	}
+	return strings.ToUpper(ap.Addresses[0].Localpart)
+}
+
+var wordDecoder = new(mime.WordDecoder)
+
+// BaseSubject extracts the base subject as defined in RFC 5256 §2.1, and
+// reports whether the subject indicated a reply or forward. The result is
+// upper-cased so that it can be compared with i;ascii-casemap semantics.
+func BaseSubject(subject string) (base string, isReplyOrForward bool) {
+	if decoded, err := wordDecoder.DecodeHeader(subject); err == nil {
+		subject = decoded
+	}
+	s := strings.ToUpper(strings.Join(strings.Fields(subject), " "))
+
+	for {
+		// (2) remove trailers
+		for {
+			trimmed := strings.TrimRight(s, " \t")
+			if strings.HasSuffix(trimmed, "(FWD)") {
+				trimmed = strings.TrimSuffix(trimmed, "(FWD)")
+				isReplyOrForward = true
+			}
+			if trimmed == s {
+				break
+			}
+			s = trimmed
+		}
+
+		// (3)-(5) remove leaders and blobs until nothing changes
+		for {
+			before := s
+			s = strings.TrimLeft(s, " \t")
+
+			if rest, ok := trimSubjectLeader(s); ok {
+				s = rest
+				isReplyOrForward = true
+			}
+			if rest, ok := trimSubjectBlob(s); ok && rest != "" {
+				s = rest
+			}
+
+			if s == before {
+				break
+			}
+		}
+
+		// (6) unwrap [fwd: ...] and start over
+		if strings.HasPrefix(s, "[FWD:") && strings.HasSuffix(s, "]") {
+			s = s[len("[FWD:") : len(s)-1]
+			isReplyOrForward = true
+			continue
+		}
+
+		return s, isReplyOrForward
+	}
+}
+
+// trimSubjectBlob removes a leading subj-blob: "[" *BLOBCHAR "]" *WSP
+func trimSubjectBlob(s string) (string, bool) {
+	if !strings.HasPrefix(s, "[") {
+		return s, false
+	}
+	end := strings.IndexAny(s[1:], "[]")
+	if end < 0 || s[1+end] != ']' {
+		return s, false
+	}
+	return strings.TrimLeft(s[end+2:], " \t"), true
+}
+
+// trimSubjectLeader removes a subj-leader: *subj-blob subj-refwd, where
+// subj-refwd is ("RE" / ("FW" ["D"])) *WSP [subj-blob] ":"
+func trimSubjectLeader(s string) (string, bool) {
+	rest := s
+	for {
+		r, ok := trimSubjectBlob(rest)
+		if !ok {
+			break
+		}
+		rest = r
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "RE"):
+		rest = rest[2:]
+	case strings.HasPrefix(rest, "FWD"):
+		rest = rest[3:]
+	case strings.HasPrefix(rest, "FW"):
+		rest = rest[2:]
+	default:
+		return s, false
+	}
+
+	rest = strings.TrimLeft(rest, " \t")
+	if r, ok := trimSubjectBlob(rest); ok {
+		rest = r
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return s, false
+	}
+	return rest[1:], true
+}
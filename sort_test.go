@@ -0,0 +1,100 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseSubject(t *testing.T) {
+	cases := []struct {
+		subject string
+		base    string
+		reply   bool
+	}{
+		{"Hello", "HELLO", false},
+		{"Re: Hello", "HELLO", true},
+		{"RE: re: Fwd: Hello", "HELLO", true},
+		{"[list] Re:  Hello   world (fwd)", "HELLO WORLD", true},
+		{"Re[2]: Hello", "HELLO", true},
+		{"[Fwd: Re: Hello]", "HELLO", true},
+		{"[list]", "[LIST]", false},
+		{"Results: final", "RESULTS: FINAL", false},
+	}
+
+	for _, c := range cases {
+		base, reply := imap.BaseSubject(c.subject)
+		assert.Equal(t, c.base, base, c.subject)
+		assert.Equal(t, c.reply, reply, c.subject)
+	}
+}
+
+func TestReadSort(t *testing.T) {
+	criteria, cs, query := NewParser(` (REVERSE DATE SUBJECT) UTF-8 SEEN`).ReadSort()
+
+	expected := []imap.SortCriterion{
+		{Key: imap.SortDate, Reverse: true},
+		{Key: imap.SortSubject},
+	}
+	assert.Equal(t, expected, criteria)
+	assert.Equal(t, "UTF-8", cs)
+	assert.Equal(t, &imap.FlagTerm{Flag: imap.FlagSeen, Present: true}, query)
+}
+
+func TestSortMessages(t *testing.T) {
+	msgs := []*imap.Message{
+		testMessage(1, "Subject", "Re: beta", "Date", "5 Jan 2024 00:00:00 +0000"),
+		testMessage(2, "Subject", "alpha"),
+		testMessage(3, "Subject", "Beta", "Date", "1 Jan 2024 00:00:00 +0000"),
+		testMessage(4, "Subject", "[list] alpha", "Date", "garbage"),
+	}
+	msgs[0].RFC822Size, msgs[1].RFC822Size, msgs[2].RFC822Size, msgs[3].RFC822Size = 30, 10, 20, 10
+
+	cases := []struct {
+		criteria []imap.SortCriterion
+		want     []int
+	}{
+		{[]imap.SortCriterion{{Key: imap.SortArrival}}, []int{1, 2, 3, 4}},
+		{[]imap.SortCriterion{{Key: imap.SortDate}}, []int{3, 2, 4, 1}},
+		{[]imap.SortCriterion{{Key: imap.SortDate, Reverse: true}}, []int{1, 4, 2, 3}},
+		{[]imap.SortCriterion{{Key: imap.SortSize}}, []int{2, 4, 3, 1}},
+		{[]imap.SortCriterion{{Key: imap.SortSubject}}, []int{2, 4, 1, 3}},
+		{[]imap.SortCriterion{{Key: imap.SortSubject}, {Key: imap.SortDate}}, []int{2, 4, 3, 1}},
+		{[]imap.SortCriterion{{Key: imap.SortSize, Reverse: true}, {Key: imap.SortArrival, Reverse: true}}, []int{1, 3, 4, 2}},
+	}
+
+	for _, c := range cases {
+		sorted := append([]*imap.Message(nil), msgs...)
+		imap.SortMessages(sorted, c.criteria)
+
+		uids := make([]int, len(sorted))
+		for i, m := range sorted {
+			uids[i] = m.UID
+		}
+		assert.Equal(t, c.want, uids, c.criteria)
+	}
+}
+
+func TestWriteThread(t *testing.T) {
+	m := map[int]*imap.Message{}
+	for _, uid := range []int{2, 3, 4, 6, 7, 23, 44, 96} {
+		m[uid] = &imap.Message{UID: uid}
+	}
+
+	threads := []*imap.Thread{
+		{Message: m[2]},
+		{Message: m[3], Children: []*imap.Thread{
+			{Message: m[6], Children: []*imap.Thread{
+				{Message: m[4], Children: []*imap.Thread{{Message: m[23]}}},
+				{Message: m[44], Children: []*imap.Thread{
+					{Message: m[7], Children: []*imap.Thread{{Message: m[96]}}},
+				}},
+			}},
+		}},
+	}
+
+	conn, out := NewTestConn("")
+	conn.WriteThread(threads, func(m *imap.Message) int { return m.UID })
+	assert.Equal(t, "* THREAD (2)(3 6 (4 23)(44 7 96))\r\n", out.String())
+}
@@ -0,0 +1,77 @@
+package imap
+
+import (
+	"strings"
+)
+
+// SPECIAL-USE and CREATE-SPECIAL-USE extensions (RFC 6154)
+
+var (
+	ExtSpecialUse       = newExtension(CapAuthenticated, "SPECIAL-USE")
+	ExtCreateSpecialUse = newExtension(CapAuthenticated, "CREATE-SPECIAL-USE")
+)
+
+const (
+	AttrAll     MailboxAttr = `\All`
+	AttrArchive             = `\Archive`
+	AttrDrafts              = `\Drafts`
+	AttrFlagged             = `\Flagged`
+	AttrJunk                = `\Junk`
+	AttrSent                = `\Sent`
+	AttrTrash               = `\Trash`
+)
+
+var SpecialUseAttrs = []MailboxAttr{
+	AttrAll,
+	AttrArchive,
+	AttrDrafts,
+	AttrFlagged,
+	AttrJunk,
+	AttrSent,
+	AttrTrash,
+}
+
+var CodeUseAttr = ResponseCode{Name: "USEATTR"}
+
+// MailboxSpecialUser is implemented by mailboxes that have a special use,
+// e.g. a localized "Gesendet" folder that is the \Sent mailbox.
+type MailboxSpecialUser interface {
+	Mailbox
+
+	SpecialUse() []MailboxAttr
+}
+
+// ParseSpecialUse returns the canonical spelling of a special-use
+// attribute, or false if it isn't one.
+func ParseSpecialUse(s string) (MailboxAttr, bool) {
+	for _, a := range SpecialUseAttrs {
+		if strings.EqualFold(s, string(a)) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// ReadCreate reads the arguments of `CREATE mailbox [(USE (attrs))]`.
+// Unknown special-use attributes are refused with `NO [USEATTR]`.
+func (p *Parser) ReadCreate() (mailbox string, specialUse []MailboxAttr) {
+	p.ReadSpace()
+	mailbox = p.ReadMailbox()
+	if !p.Valid() || !p.accept(" (") {
+		return
+	}
+	p.listDepth++
+
+	p.Expect("USE ")
+	for _, s := range p.ReadAtomList() {
+		attr, ok := ParseSpecialUse(s)
+		if !ok {
+			p.err = NewResponseError(CodeUseAttr, "unsupported special-use attribute "+s)
+			return mailbox, nil
+		}
+		specialUse = append(specialUse, attr)
+	}
+
+	p.ReadListEnd()
+	return mailbox, specialUse
+}
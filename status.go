@@ -0,0 +1,149 @@
+package imap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// STATUS (RFC 3501 §6.3.10), including SIZE (RFC 8438) and HIGHESTMODSEQ
+// (RFC 7162).
+
+var ExtStatusSize = newExtension(CapAuthenticated, "STATUS=SIZE")
+
+// StatusItems is the set of items requested by a STATUS command.
+type StatusItems struct {
+	Messages      bool
+	Recent        bool
+	UIDNext       bool
+	UIDValidity   bool
+	Unseen        bool
+	Size          bool
+	HighestModSeq bool
+}
+
+// StatusData holds the values of a STATUS response. Only the fields for
+// requested items are sent.
+type StatusData struct {
+	Messages      int
+	Recent        int
+	UIDNext       int
+	UIDValidity   int
+	Unseen        int
+	Size          int64
+	HighestModSeq uint64
+}
+
+// MailboxStatuser is implemented by mailboxes that can answer STATUS.
+// Clients poll STATUS on every mailbox, so implementations should only
+// compute the requested items.
+type MailboxStatuser interface {
+	Mailbox
+
+	Status(items *StatusItems) (*StatusData, error)
+}
+
+// ParseStatusItems converts a list of status item names, as read by
+// ReadStatus or LIST RETURN (STATUS (...)).
+func ParseStatusItems(names []string) (*StatusItems, error) {
+	items := &StatusItems{}
+	for _, name := range names {
+		switch strings.ToUpper(name) {
+		case "MESSAGES":
+			items.Messages = true
+		case "RECENT":
+			items.Recent = true
+		case "UIDNEXT":
+			items.UIDNext = true
+		case "UIDVALIDITY":
+			items.UIDValidity = true
+		case "UNSEEN":
+			items.Unseen = true
+		case "SIZE":
+			items.Size = true
+		case "HIGHESTMODSEQ":
+			items.HighestModSeq = true
+		default:
+			return nil, ProtocolErrorf("unknown status item %q", name)
+		}
+	}
+	return items, nil
+}
+
+// ReadStatus reads the arguments of `STATUS mailbox (items)`.
+func (p *Parser) ReadStatus() (mailbox string, items *StatusItems) {
+	p.ReadSpace()
+	mailbox = p.ReadMailbox()
+	p.ReadSpace()
+	if !p.Valid() {
+		return "", nil
+	}
+
+	names := p.readOptionList()
+	if !p.Valid() {
+		return "", nil
+	}
+	if len(names) == 0 {
+		p.err = ProtocolError("STATUS requires at least one item")
+		return "", nil
+	}
+
+	items, err := ParseStatusItems(names)
+	if err != nil {
+		p.err = err
+		return "", nil
+	}
+	return mailbox, items
+}
+
+func (d *StatusData) format(items *StatusItems) string {
+	var list []string
+	add := func(name string, value string) {
+		list = append(list, name, value)
+	}
+
+	if items.Messages {
+		add("MESSAGES", strconv.Itoa(d.Messages))
+	}
+	if items.Recent {
+		add("RECENT", strconv.Itoa(d.Recent))
+	}
+	if items.UIDNext {
+		add("UIDNEXT", strconv.Itoa(d.UIDNext))
+	}
+	if items.UIDValidity {
+		add("UIDVALIDITY", strconv.Itoa(d.UIDValidity))
+	}
+	if items.Unseen {
+		add("UNSEEN", strconv.Itoa(d.Unseen))
+	}
+	if items.Size {
+		add("SIZE", strconv.FormatInt(d.Size, 10))
+	}
+	if items.HighestModSeq {
+		add("HIGHESTMODSEQ", strconv.FormatUint(d.HighestModSeq, 10))
+	}
+	return "(" + strings.Join(list, " ") + ")"
+}
+
+// WriteStatus sends a STATUS response for a mailbox, with the requested
+// items in a fixed order.
+func (c *Conn) WriteStatus(name string, items *StatusItems, data *StatusData) {
+	c.Splat(fmt.Sprintf("STATUS %s %s", quoteString(c.encodeMailbox(name)), data.format(items)))
+}
+
+// Status answers a STATUS command through the backend and completes the
+// request. The user needs the "r" right on the mailbox.
+func (c *Conn) Status(r *Request, mbox MailboxStatuser, items *StatusItems) error {
+	if err := c.Authorize(mbox.Name(), CommandRights("STATUS")); err != nil {
+		return err
+	}
+	data, err := mbox.Status(items)
+	if err != nil {
+		return err
+	}
+
+	c.WriteStatus(mbox.Name(), items, data)
+	c.Ok(r)
+	return nil
+}
@@ -0,0 +1,30 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadStatus(t *testing.T) {
+	name, items := NewParser(` blurdybloop (UIDNEXT messages SIZE)`).ReadStatus()
+
+	assert.Equal(t, "blurdybloop", name)
+	assert.Equal(t, &imap.StatusItems{Messages: true, UIDNext: true, Size: true}, items)
+}
+
+func TestReadStatusUnknownItem(t *testing.T) {
+	p := NewParser(` INBOX (MESSAGES DELETED)`)
+	p.ReadStatus()
+	assert.Error(t, p.Err())
+}
+
+func TestWriteStatus(t *testing.T) {
+	conn, out := NewTestConn("")
+	items := &imap.StatusItems{UIDNext: true, Messages: true}
+	data := &imap.StatusData{Messages: 231, UIDNext: 44292, Recent: 3}
+
+	conn.WriteStatus("blurdybloop", items, data)
+	assert.Equal(t, "* STATUS \"blurdybloop\" (MESSAGES 231 UIDNEXT 44292)\r\n", out.String())
+}
@@ -0,0 +1,156 @@
+package imap
+
+import (
+	"fmt"
+	"strings"
+)
+
+type StoreOp int
+
+const (
+	StoreReplace StoreOp = iota // FLAGS
+	StoreAdd                    // +FLAGS
+	StoreRemove                 // -FLAGS
+)
+
+type StoreCommand struct {
+	Set    *SequenceSet
+	Op     StoreOp
+	Silent bool
+	Flags  []Flag
+
+	// UnchangedSince is the CONDSTORE UNCHANGEDSINCE modifier, if
+	// HasUnchangedSince is set.
+	UnchangedSince    uint64
+	HasUnchangedSince bool
+}
+
+// ReadStore reads the arguments of `STORE <sequence-set> [(UNCHANGEDSINCE n)]
+// [+|-]FLAGS[.SILENT] <flags>`. The flags may be a parenthesized list or
+// space-separated. Handlers check the user's rights with
+// Conn.AuthorizeStore before applying it.
+func (p *Parser) ReadStore() *StoreCommand {
+	cmd := &StoreCommand{}
+
+	p.ReadSpace()
+	cmd.Set = p.ReadSequenceSet()
+	cmd.UnchangedSince, cmd.HasUnchangedSince = p.ReadStoreModifiers()
+	p.ReadSpace()
+	if !p.Valid() {
+		return nil
+	}
+
+	if p.accept("+") {
+		cmd.Op = StoreAdd
+	} else if p.accept("-") {
+		cmd.Op = StoreRemove
+	}
+	p.Expect("FLAGS")
+	cmd.Silent = p.accept(".SILENT")
+	p.ReadSpace()
+	if !p.Valid() {
+		return nil
+	}
+
+	if p.Peek() == '(' {
+		cmd.Flags = p.ReadFlagList()
+	} else {
+		cmd.Flags = p.readFlags()
+	}
+
+	if !p.Valid() {
+		return nil
+	}
+	return cmd
+}
+
+// readFlags reads space-separated flags without surrounding parentheses.
+func (p *Parser) readFlags() []Flag {
+	list := make([]Flag, 0)
+	for {
+		f, err := parseFlag(p.ReadAtom())
+		if !p.Valid() {
+			return nil
+		} else if err != nil {
+			p.err = err
+			return nil
+		}
+		list = append(list, f)
+
+		if !p.accept(" ") {
+			return list
+		}
+	}
+}
+
+// Unmodified reports whether msg passes the UNCHANGEDSINCE test, i.e.
+// whether the STORE may be applied to it.
+func (cmd *StoreCommand) Unmodified(msg *Message) bool {
+	return !cmd.HasUnchangedSince || msg.ModSeq <= cmd.UnchangedSince
+}
+
+// Apply performs the operation on msg.Flags and reports whether anything
+// changed.
+func (cmd *StoreCommand) Apply(msg *Message) bool {
+	switch cmd.Op {
+	case StoreAdd:
+		changed := false
+		for _, f := range cmd.Flags {
+			if !hasFlag(msg.Flags, f) {
+				msg.Flags = append(msg.Flags, f)
+				changed = true
+			}
+		}
+		return changed
+	case StoreRemove:
+		kept := msg.Flags[:0]
+		for _, f := range msg.Flags {
+			if !hasFlag(cmd.Flags, f) {
+				kept = append(kept, f)
+			}
+		}
+		changed := len(kept) != len(msg.Flags)
+		msg.Flags = kept
+		return changed
+	default:
+		changed := len(msg.Flags) != len(cmd.Flags)
+		for _, f := range cmd.Flags {
+			if !hasFlag(msg.Flags, f) {
+				changed = true
+			}
+		}
+		msg.Flags = append([]Flag(nil), cmd.Flags...)
+		return changed
+	}
+}
+
+func hasFlag(flags []Flag, f Flag) bool {
+	for _, g := range flags {
+		if g.Equal(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteStoreResult sends the untagged FETCH response for a message updated
+// by STORE. Once CONDSTORE is enabled, the response includes the new MODSEQ
+// (RFC 7162 §3.1.3). Nothing is sent for .SILENT, unless UNCHANGEDSINCE was
+// given, in which case the MODSEQ is reported regardless.
+func (c *Conn) WriteStoreResult(r *Request, cmd *StoreCommand, seq int, msg *Message) {
+	if cmd.Silent && !cmd.HasUnchangedSince {
+		return
+	}
+
+	items := []string{}
+	if !cmd.Silent {
+		items = append(items, "FLAGS "+MarshalFlags(msg))
+	}
+	if r.UID {
+		items = append(items, "UID "+MarshalUID(msg))
+	}
+	if cmd.HasUnchangedSince || c.Enabled("CONDSTORE") {
+		items = append(items, "MODSEQ "+MarshalModSeq(msg))
+	}
+	fmt.Fprintf(c, "* %d FETCH (%s)\r\n", seq, strings.Join(items, " "))
+}
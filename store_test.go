@@ -0,0 +1,129 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadStore(t *testing.T) {
+	cmd := NewParser(` 2:4 +FLAGS.SILENT (\Seen \Flagged)`).ReadStore()
+	if !assert.NotNil(t, cmd) {
+		return
+	}
+
+	assert.Equal(t, "2:4", cmd.Set.String())
+	assert.Equal(t, imap.StoreAdd, cmd.Op)
+	assert.True(t, cmd.Silent)
+	assert.Equal(t, []imap.Flag{imap.FlagSeen, imap.FlagFlagged}, cmd.Flags)
+	assert.False(t, cmd.HasUnchangedSince)
+}
+
+func TestReadStoreUnparenthesized(t *testing.T) {
+	cmd := NewParser(` 1 (UNCHANGEDSINCE 320162338) -flags \Answered \Draft`).ReadStore()
+	if !assert.NotNil(t, cmd) {
+		return
+	}
+
+	assert.Equal(t, imap.StoreRemove, cmd.Op)
+	assert.False(t, cmd.Silent)
+	assert.Equal(t, []imap.Flag{imap.FlagAnswered, imap.FlagDraft}, cmd.Flags)
+	assert.True(t, cmd.HasUnchangedSince)
+	assert.Equal(t, uint64(320162338), cmd.UnchangedSince)
+}
+
+func TestStoreApply(t *testing.T) {
+	msg := &imap.Message{Flags: []imap.Flag{imap.FlagSeen}}
+
+	add := &imap.StoreCommand{Op: imap.StoreAdd, Flags: []imap.Flag{imap.FlagSeen}}
+	assert.False(t, add.Apply(msg), "adding a flag that is already set changes nothing")
+
+	add.Flags = []imap.Flag{imap.FlagFlagged}
+	assert.True(t, add.Apply(msg))
+	assert.Equal(t, []imap.Flag{imap.FlagSeen, imap.FlagFlagged}, msg.Flags)
+
+	remove := &imap.StoreCommand{Op: imap.StoreRemove, Flags: []imap.Flag{imap.FlagSeen}}
+	assert.True(t, remove.Apply(msg))
+	assert.Equal(t, []imap.Flag{imap.FlagFlagged}, msg.Flags)
+
+	replace := &imap.StoreCommand{Op: imap.StoreReplace, Flags: []imap.Flag{imap.FlagFlagged}}
+	assert.False(t, replace.Apply(msg))
+}
+
+func TestWriteStoreResult(t *testing.T) {
+	conn, out := NewTestConn("A3 UID STORE 42 FLAGS (\\Seen)")
+	req, _ := conn.ReadRequest()
+	cmd := req.ReadStore()
+
+	msg := &imap.Message{UID: 42, Flags: []imap.Flag{imap.FlagSeen}}
+	conn.WriteStoreResult(req, cmd, 3, msg)
+	assert.Equal(t, "* 3 FETCH (FLAGS (\\Seen) UID 42)\r\n", out.String())
+}
+
+func TestReadFlagListKeywords(t *testing.T) {
+	p := NewParser(`(\Seen $Junk $Label1 NonJunk)`)
+	flags := p.ReadFlagList()
+
+	assert.NoError(t, p.Err())
+	assert.Equal(t, []imap.Flag{imap.FlagSeen, "$Junk", "$Label1", "NonJunk"}, flags)
+
+	p = NewParser(`(\Bogus)`)
+	p.ReadFlagList()
+	assert.Error(t, p.Err(), "unknown system flags are rejected")
+}
+
+func TestIsPermanent(t *testing.T) {
+	perm := []imap.Flag{imap.FlagSeen, imap.FlagWildcard}
+	assert.True(t, imap.IsPermanent(perm, imap.FlagSeen))
+	assert.True(t, imap.IsPermanent(perm, "$Junk"))
+	assert.False(t, imap.IsPermanent(perm, imap.FlagDraft))
+	assert.False(t, imap.IsPermanent([]imap.Flag{imap.FlagSeen}, "$Junk"))
+}
+
+func TestFlagCaseNormalization(t *testing.T) {
+	p := NewParser(`(\SEEN \deleted \Flagged)`)
+	flags := p.ReadFlagList()
+
+	assert.NoError(t, p.Err())
+	assert.Equal(t, []imap.Flag{imap.FlagSeen, imap.FlagDeleted, imap.FlagFlagged}, flags)
+
+	term := &imap.FlagTerm{Flag: "$junk", Present: true}
+	assert.True(t, term.Matches([]imap.Flag{"$Junk"}))
+
+	_, query := NewParser(` DELETED`).ReadSearch()
+	assert.Equal(t, &imap.FlagTerm{Flag: `\Deleted`, Present: true}, query)
+}
+
+func TestWriteStoreResultCondStore(t *testing.T) {
+	conn, out := NewTestConn("A1 ENABLE CONDSTORE\r\nA2 STORE 3 +FLAGS (\\Seen)")
+	conn.SetState(imap.StateAuthenticated)
+	conn.Capabilities().Enable(imap.ExtCondStore, imap.ExtEnable)
+
+	req, _ := conn.ReadRequest()
+	caps := req.ReadEnable()
+	req.ReadEOL()
+	conn.Enable(req, caps)
+
+	out.Reset()
+	req, _ = conn.ReadRequest()
+	cmd := req.ReadStore()
+
+	msg := &imap.Message{UID: 42, ModSeq: 320162350, Flags: []imap.Flag{imap.FlagSeen}}
+	conn.WriteStoreResult(req, cmd, 3, msg)
+	assert.Equal(t, "* 3 FETCH (FLAGS (\\Seen) MODSEQ (320162350))\r\n", out.String())
+}
+
+func TestRecentCannotBeSet(t *testing.T) {
+	for _, input := range []string{` 1 +FLAGS (\Recent)`, ` 1 FLAGS \Seen \recent`} {
+		p := NewParser(input)
+		assert.Nil(t, p.ReadStore(), input)
+		assert.Error(t, p.Err(), input)
+	}
+
+	conn, _ := NewTestConn("A1 APPEND INBOX (\\Seen \\Recent) {3+}\r\nabc")
+	req, _ := conn.ReadRequest()
+	req.ReadAppendMailbox()
+	assert.Nil(t, req.ReadAppendMessage())
+	assert.Error(t, req.Err())
+}
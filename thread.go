@@ -0,0 +1,395 @@
+package imap
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// THREAD extension (RFC 5256)
+
+const (
+	ThreadOrderedSubject = "ORDEREDSUBJECT"
+	ThreadReferences     = "REFERENCES"
+)
+
+var ExtThread = newExtension(CapAuthenticated, "THREAD="+ThreadOrderedSubject, "THREAD="+ThreadReferences)
+
+// Thread is a node in a thread tree. Message is nil for dummy nodes, which
+// stand in for messages that are referenced but not present.
+type Thread struct {
+	Message  *Message
+	Children []*Thread
+}
+
+// ReadThread reads the arguments of `THREAD algorithm charset search-keys`.
+func (p *Parser) ReadThread() (algorithm string, charset string, query Term) {
+	p.ReadSpace()
+	algorithm = strings.ToUpper(p.ReadAtom())
+	if !p.Valid() {
+		return
+	}
+	if algorithm != ThreadOrderedSubject && algorithm != ThreadReferences {
+		p.err = ProtocolErrorf("unknown thread algorithm %q", algorithm)
+		return
+	}
+
+	p.ReadSpace()
+	charset = p.ReadAstring()
+	p.ReadSpace()
+	if !p.Valid() {
+		return "", "", nil
+	}
+
+	return algorithm, charset, p.readSearchKeys()
+}
+
+// ThreadMessages threads msgs with the named algorithm. msgs should be in
+// sequence number order.
+func ThreadMessages(algorithm string, msgs []*Message) []*Thread {
+	switch algorithm {
+	case ThreadOrderedSubject:
+		return ThreadByOrderedSubject(msgs)
+	case ThreadReferences:
+		return ThreadByReferences(msgs)
+	}
+	return nil
+}
+
+// ThreadByOrderedSubject implements the ORDEREDSUBJECT algorithm: messages
+// are grouped by base subject, and the earliest message of each group is the
+// parent of the rest.
+func ThreadByOrderedSubject(msgs []*Message) []*Thread {
+	sorted := make([]*Message, len(msgs))
+	copy(sorted, msgs)
+	SortMessages(sorted, []SortCriterion{{Key: SortSubject}, {Key: SortDate}})
+
+	var threads []*Thread
+	var current *Thread
+	lastSubject := ""
+	for _, m := range sorted {
+		subject, _ := BaseSubject(m.Header.Get("Subject"))
+		if current != nil && subject == lastSubject {
+			current.Children = append(current.Children, &Thread{Message: m})
+			continue
+		}
+
+		current = &Thread{Message: m}
+		threads = append(threads, current)
+		lastSubject = subject
+	}
+
+	sortThreads(threads)
+	return threads
+}
+
+type threadContainer struct {
+	Message  *Message
+	parent   *threadContainer
+	children []*threadContainer
+}
+
+func (c *threadContainer) hasDescendant(d *threadContainer) bool {
+	for _, child := range c.children {
+		if child == d || child.hasDescendant(d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *threadContainer) setParent(parent *threadContainer) {
+	if c.parent != nil {
+		siblings := c.parent.children
+		for i, s := range siblings {
+			if s == c {
+				c.parent.children = append(siblings[:i], siblings[i+1:]...)
+				break
+			}
+		}
+	}
+
+	c.parent = parent
+	if parent != nil {
+		parent.children = append(parent.children, c)
+	}
+}
+
+func (c *threadContainer) toThread() *Thread {
+	t := &Thread{Message: c.Message}
+	for _, child := range c.children {
+		t.Children = append(t.Children, child.toThread())
+	}
+	return t
+}
+
+// ThreadByReferences implements the REFERENCES algorithm (RFC 5256 §3).
+func ThreadByReferences(msgs []*Message) []*Thread {
+	// (1) link messages by their Message-ID and References headers
+	ids := map[string]*threadContainer{}
+	all := []*threadContainer{}
+	container := func(id string) *threadContainer {
+		c, ok := ids[id]
+		if !ok {
+			c = &threadContainer{}
+			ids[id] = c
+			all = append(all, c)
+		}
+		return c
+	}
+
+	for i, m := range msgs {
+		id := firstMessageID(m.Header.Get("Message-Id"))
+		if id == "" || (ids[id] != nil && ids[id].Message != nil) {
+			id = fmt.Sprintf("<go-imap-unique-%d>", i) // missing or duplicate
+		}
+		c := container(id)
+		c.Message = m
+
+		refs := messageIDs(m.Header.Get("References"))
+		if len(refs) == 0 {
+			if irt := firstMessageID(m.Header.Get("In-Reply-To")); irt != "" {
+				refs = []string{irt}
+			}
+		}
+
+		var prev *threadContainer
+		for _, ref := range refs {
+			rc := container(ref)
+			if prev != nil && rc.parent == nil && rc != prev &&
+				!rc.hasDescendant(prev) {
+				rc.setParent(prev)
+			}
+			prev = rc
+		}
+
+		if prev == c || (prev != nil && c.hasDescendant(prev)) {
+			prev = nil
+		}
+		c.setParent(prev)
+	}
+
+	// (2) gather the root set
+	var roots []*threadContainer
+	for _, c := range all {
+		if c.parent == nil {
+			roots = append(roots, c)
+		}
+	}
+
+	// (4) prune dummies
+	roots = pruneContainers(roots, true)
+
+	// (5) sort the root set, and each set of siblings, by sent date
+	order := map[*Message]int{}
+	for i, m := range msgs {
+		order[m] = i
+	}
+	sortContainers(roots, order)
+
+	// (6) merge threads with the same base subject
+	subjects := map[string]*threadContainer{}
+	subjectOf := func(c *threadContainer) (string, bool) {
+		m := c.Message
+		if m == nil && len(c.children) > 0 {
+			m = c.children[0].Message
+		}
+		if m == nil {
+			return "", false
+		}
+		return BaseSubject(m.Header.Get("Subject"))
+	}
+
+	for _, c := range roots {
+		subject, isReply := subjectOf(c)
+		if subject == "" {
+			continue
+		}
+
+		old, ok := subjects[subject]
+		if !ok {
+			subjects[subject] = c
+			continue
+		}
+		_, oldIsReply := subjectOf(old)
+		if old.Message != nil && (c.Message == nil || (oldIsReply && !isReply)) {
+			subjects[subject] = c
+		}
+	}
+
+	replaced := map[*threadContainer]*threadContainer{}
+	deleted := map[*threadContainer]bool{}
+	for _, c := range roots {
+		if c.parent != nil {
+			continue // already merged into another thread
+		}
+
+		subject, isReply := subjectOf(c)
+		other, ok := subjects[subject]
+		if subject == "" || !ok || other == c {
+			continue
+		}
+
+		_, otherIsReply := subjectOf(other)
+		switch {
+		case c.Message == nil && other.Message == nil:
+			for _, child := range append([]*threadContainer(nil), c.children...) {
+				child.setParent(other)
+			}
+			deleted[c] = true
+		case other.Message == nil:
+			c.setParent(other)
+		case isReply && !otherIsReply:
+			c.setParent(other)
+		default:
+			dummy := &threadContainer{}
+			other.setParent(dummy)
+			c.setParent(dummy)
+			replaced[other] = dummy
+			subjects[subject] = dummy
+		}
+	}
+
+	var merged []*threadContainer
+	for _, c := range roots {
+		if d, ok := replaced[c]; ok {
+			c = d
+		}
+		if c.parent == nil && !deleted[c] {
+			merged = append(merged, c)
+		}
+	}
+
+	// (7) sort siblings again, now that threads have been merged
+	for _, c := range merged {
+		sortContainers(c.children, order)
+	}
+
+	threads := make([]*Thread, len(merged))
+	for i, c := range merged {
+		threads[i] = c.toThread()
+	}
+	return threads
+}
+
+// pruneContainers removes empty dummy containers and promotes the children
+// of dummies, except for dummies at the root with more than one child.
+func pruneContainers(list []*threadContainer, root bool) []*threadContainer {
+	var out []*threadContainer
+	for _, c := range list {
+		c.children = pruneContainers(c.children, false)
+		for _, child := range c.children {
+			child.parent = c
+		}
+
+		if c.Message != nil {
+			out = append(out, c)
+		} else if len(c.children) == 0 {
+			continue
+		} else if root && len(c.children) > 1 {
+			out = append(out, c)
+		} else {
+			for _, child := range c.children {
+				child.parent = c.parent
+			}
+			out = append(out, c.children...)
+		}
+	}
+	return out
+}
+
+func containerDate(c *threadContainer) *Message {
+	if c.Message != nil {
+		return c.Message
+	}
+	if len(c.children) > 0 {
+		return containerDate(c.children[0])
+	}
+	return nil
+}
+
+// sortContainers sorts siblings by sent date, recursively. Ties are broken
+// by mailbox order.
+func sortContainers(list []*threadContainer, order map[*Message]int) {
+	for _, c := range list {
+		sortContainers(c.children, order)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := containerDate(list[i]), containerDate(list[j])
+		if a == nil || b == nil {
+			return false
+		}
+		if da, db := SentDate(a), SentDate(b); !da.Equal(db) {
+			return da.Before(db)
+		}
+		return order[a] < order[b]
+	})
+}
+
+func sortThreads(threads []*Thread) {
+	sort.SliceStable(threads, func(i, j int) bool {
+		return SentDate(threads[i].Message).Before(SentDate(threads[j].Message))
+	})
+}
+
+func messageIDs(header string) []string {
+	var ids []string
+	for {
+		start := strings.IndexByte(header, '<')
+		if start < 0 {
+			return ids
+		}
+		end := strings.IndexByte(header[start:], '>')
+		if end < 0 {
+			return ids
+		}
+		ids = append(ids, header[start:start+end+1])
+		header = header[start+end+1:]
+	}
+}
+
+func firstMessageID(header string) string {
+	ids := messageIDs(header)
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
+
+// WriteThread sends the THREAD response. id maps a message to the number
+// reported for it: its sequence number, or its UID for UID THREAD.
+func (c *Conn) WriteThread(threads []*Thread, id func(*Message) int) {
+	var buf bytes.Buffer
+	buf.WriteString("THREAD ")
+	for _, t := range threads {
+		buf.WriteByte('(')
+		writeThread(&buf, t, id)
+		buf.WriteByte(')')
+	}
+	c.Splat(strings.TrimRight(buf.String(), " "))
+}
+
+// writeThread writes a single thread. A node with one child continues the
+// chain; a node with several children wraps each one in parentheses.
+func writeThread(buf *bytes.Buffer, t *Thread, id func(*Message) int) {
+	if t.Message != nil {
+		buf.WriteString(strconv.Itoa(id(t.Message)))
+		if len(t.Children) > 0 {
+			buf.WriteByte(' ')
+		}
+	}
+
+	if len(t.Children) == 1 && t.Message != nil {
+		writeThread(buf, t.Children[0], id)
+		return
+	}
+
+	for _, child := range t.Children {
+		buf.WriteByte('(')
+		writeThread(buf, child, id)
+		buf.WriteByte(')')
+	}
+}
@@ -0,0 +1,216 @@
+package imap_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/paulrosania/go-mail"
+	"github.com/stretchr/testify/assert"
+)
+
+// testMessage builds a message from header name/value pairs. Its internal
+// date is day uid of January 2024, so messages without a Date header sort
+// by UID.
+func testMessage(uid int, fields ...string) *imap.Message {
+	h := &mail.Header{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		h.Fields = append(h.Fields, mail.NewField(fields[i], fields[i+1]))
+	}
+
+	m := &imap.Message{UID: uid, ReceivedAt: time.Date(2024, time.January, uid, 0, 0, 0, 0, time.UTC)}
+	m.Header = h
+	return m
+}
+
+func formatThreads(threads []*imap.Thread) string {
+	conn, out := NewTestConn("")
+	conn.WriteThread(threads, func(m *imap.Message) int { return m.UID })
+	return strings.TrimSuffix(strings.TrimPrefix(out.String(), "* THREAD "), "\r\n")
+}
+
+func TestThreadByOrderedSubject(t *testing.T) {
+	cases := []struct {
+		name string
+		msgs []*imap.Message
+		want string
+	}{
+		{
+			"single thread",
+			[]*imap.Message{
+				testMessage(1, "Subject", "Hello"),
+				testMessage(2, "Subject", "Re: hello"),
+			},
+			"(1 2)",
+		},
+		{
+			"groups by base subject, ordered by date",
+			[]*imap.Message{
+				testMessage(1, "Subject", "Re: S", "Date", "3 Jan 2024 00:00:00 +0000"),
+				testMessage(2, "Subject", "T", "Date", "2 Jan 2024 00:00:00 +0000"),
+				testMessage(3, "Subject", "S", "Date", "1 Jan 2024 00:00:00 +0000"),
+				testMessage(4, "Subject", "[list] s", "Date", "4 Jan 2024 00:00:00 +0000"),
+			},
+			"(3 (1)(4))(2)",
+		},
+		{
+			"falls back to the internal date",
+			[]*imap.Message{
+				testMessage(1, "Subject", "A", "Date", "10 Jan 2024 00:00:00 +0000"),
+				testMessage(2, "Subject", "B"),
+				testMessage(3, "Subject", "C", "Date", "garbage"),
+			},
+			"(2)(3)(1)",
+		},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, formatThreads(imap.ThreadByOrderedSubject(c.msgs)), c.name)
+	}
+}
+
+func TestThreadByReferences(t *testing.T) {
+	cases := []struct {
+		name string
+		msgs []*imap.Message
+		want string
+	}{
+		{
+			"chain",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<b>", "References", "<a>", "Subject", "Re: S"),
+				testMessage(3, "Message-Id", "<c>", "References", "<a> <b>", "Subject", "Re: S"),
+			},
+			"(1 2 3)",
+		},
+		{
+			"In-Reply-To without References",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<b>", "In-Reply-To", "<a>", "Subject", "T"),
+			},
+			"(1 2)",
+		},
+		{
+			"dummy with one child is promoted",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<b>", "References", "<missing>", "Subject", "S"),
+			},
+			"(1)",
+		},
+		{
+			"dummy root with several children is kept",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<b>", "References", "<missing>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<c>", "References", "<missing>", "Subject", "T"),
+			},
+			"((1)(2))",
+		},
+		{
+			"dummy in the middle of a chain is promoted",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<c>", "References", "<a> <missing>", "Subject", "T"),
+			},
+			"(1 2)",
+		},
+		{
+			"duplicate Message-ID",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<a>", "Subject", "T"),
+				testMessage(3, "Message-Id", "<b>", "References", "<a>", "Subject", "U"),
+			},
+			"(1 3)(2)",
+		},
+		{
+			"reference loop",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "References", "<b>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<b>", "References", "<a>", "Subject", "T"),
+			},
+			"(2 1)",
+		},
+		{
+			"self reference",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "References", "<a>", "Subject", "S"),
+			},
+			"(1)",
+		},
+		{
+			"roots sorted by sent date, falling back to the internal date",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "S", "Date", "10 Jan 2024 00:00:00 +0000"),
+				testMessage(2, "Message-Id", "<b>", "Subject", "T"),
+			},
+			"(2)(1)",
+		},
+		{
+			"subject merge: reply joins the original",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<b>", "Subject", "Re: S"),
+			},
+			"(1 2)",
+		},
+		{
+			"subject merge: original found after the reply",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "Re: S"),
+				testMessage(2, "Message-Id", "<b>", "Subject", "S"),
+			},
+			"(2 1)",
+		},
+		{
+			"subject merge: neither is a reply",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<b>", "Subject", "s"),
+			},
+			"((1)(2))",
+		},
+		{
+			"subject merge: message joins a dummy",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<b1>", "References", "<x>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<b2>", "References", "<x>", "Subject", "S"),
+				testMessage(3, "Message-Id", "<c>", "Subject", "Re: S"),
+			},
+			"((1)(2)(3))",
+		},
+		{
+			"subject merge: dummy found after the message",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<b1>", "References", "<x>", "Subject", "Re: S"),
+				testMessage(3, "Message-Id", "<b2>", "References", "<x>", "Subject", "Re: S"),
+			},
+			"((1)(2)(3))",
+		},
+		{
+			"subject merge: two dummies",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<b1>", "References", "<x>", "Subject", "S"),
+				testMessage(2, "Message-Id", "<b2>", "References", "<x>", "Subject", "S"),
+				testMessage(3, "Message-Id", "<c1>", "References", "<y>", "Subject", "Re: S"),
+				testMessage(4, "Message-Id", "<c2>", "References", "<y>", "Subject", "S"),
+			},
+			"((1)(2)(3)(4))",
+		},
+		{
+			"no subject merging for empty subjects",
+			[]*imap.Message{
+				testMessage(1, "Message-Id", "<a>"),
+				testMessage(2, "Message-Id", "<b>"),
+			},
+			"(1)(2)",
+		},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, formatThreads(imap.ThreadByReferences(c.msgs)), c.name)
+	}
+}
@@ -0,0 +1,70 @@
+package imap
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// UIDPLUS extension (RFC 4315)
+
+var ExtUIDPlus = newExtension(CapAuthenticated, "UIDPLUS")
+
+func CodeAppendUID(uidValidity int, uids *SequenceSet) ResponseCode {
+	return ResponseCode{
+		Name: "APPENDUID",
+		Args: []string{strconv.Itoa(uidValidity), uids.String()},
+	}
+}
+
+func CodeCopyUID(uidValidity int, src, dst *SequenceSet) ResponseCode {
+	return ResponseCode{
+		Name: "COPYUID",
+		Args: []string{strconv.Itoa(uidValidity), src.String(), dst.String()},
+	}
+}
+
+var CodeUIDNotSticky = ResponseCode{Name: "UIDNOTSTICKY"}
+
+// ReadUIDExpunge reads the arguments of `UID EXPUNGE <sequence-set>`.
+func (p *Parser) ReadUIDExpunge() *SequenceSet {
+	p.ReadSpace()
+	return p.ReadSequenceSet()
+}
+
+func (c *Conn) WriteExpunge(seq int) {
+	fmt.Fprintf(c, "* %d EXPUNGE\r\n", seq)
+}
+
+// Expunge runs EXPUNGE (or UID EXPUNGE, if uids is non-nil) against the
+// backend and writes the untagged EXPUNGE responses and the tagged OK.
+func (c *Conn) Expunge(r *Request, mbox MailboxExpunger, uids *SequenceSet) error {
+	seqs, err := mbox.Expunge(uids)
+	if err != nil {
+		return err
+	}
+
+	for _, seq := range seqs {
+		c.WriteExpunge(seq)
+	}
+	c.Ok(r)
+	return nil
+}
+
+// Copy copies messages through the backend and completes the request with
+// a COPYUID response code. The user needs the "i" right on dest.
+func (c *Conn) Copy(r *Request, mbox MailboxCopier, seqs *SequenceSet, dest string) error {
+	if err := c.Authorize(dest, CommandRights("COPY")); err != nil {
+		return err
+	}
+	res, err := mbox.Copy(seqs, r.UID, dest)
+	if err != nil {
+		return err
+	}
+
+	if res == nil || res.Source.Len() == 0 {
+		c.Ok(r) // nothing copied, so there are no UIDs to report
+	} else {
+		c.OkWithCode(r, res.ResponseCode())
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadUIDExpunge(t *testing.T) {
+	conn := imap.NewConn(NewBuffer("a1 uid expunge 3000:3002,3005"))
+	req, err := conn.ReadRequest()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, req.UID)
+	assert.Equal(t, "EXPUNGE", req.Command)
+	assert.Equal(t, "UID EXPUNGE", req.Name())
+
+	set := req.ReadUIDExpunge()
+	assert.NoError(t, req.Err())
+	assert.Equal(t, "3000:3002,3005", set.String())
+}
+
+func TestCopyUIDCode(t *testing.T) {
+	src := imap.NewSequenceSet([]int{304, 305, 306, 309, 308})
+	dst := imap.NewSequenceSet([]int{3956, 3957, 3958, 3959, 3960})
+
+	code := imap.CodeCopyUID(38505, src, dst)
+	assert.Equal(t, "COPYUID 38505 304:306,309,308 3956:3960", code.String())
+}
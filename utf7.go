@@ -0,0 +1,157 @@
+package imap
+
+import (
+	"encoding/base64"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Modified UTF-7 mailbox name encoding (RFC 3501 §5.1.3)
+
+var mutf7 = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+,").WithPadding(base64.NoPadding)
+
+func isDirectChar(r rune) bool {
+	return r >= 0x20 && r <= 0x7e
+}
+
+// EncodeMailboxName converts a UTF-8 mailbox name to modified UTF-7.
+func EncodeMailboxName(name string) string {
+	var b strings.Builder
+	var run []rune
+
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		u := utf16.Encode(run)
+		buf := make([]byte, 2*len(u))
+		for i, c := range u {
+			buf[2*i] = byte(c >> 8)
+			buf[2*i+1] = byte(c)
+		}
+		b.WriteByte('&')
+		b.WriteString(mutf7.EncodeToString(buf))
+		b.WriteByte('-')
+		run = run[:0]
+	}
+
+	for _, r := range name {
+		if isDirectChar(r) {
+			flush()
+			if r == '&' {
+				b.WriteString("&-")
+			} else {
+				b.WriteRune(r)
+			}
+		} else {
+			run = append(run, r)
+		}
+	}
+	flush()
+
+	return b.String()
+}
+
+// DecodeMailboxName converts a modified UTF-7 mailbox name to UTF-8. It
+// rejects names that aren't in canonical form: raw 8-bit bytes,
+// unterminated or adjacent shift sequences, and printable ASCII that was
+// needlessly encoded.
+func DecodeMailboxName(name string) (string, error) {
+	var b strings.Builder
+	afterShift := false
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 0x20 || c > 0x7e {
+			return "", ProtocolErrorf("invalid byte %q in mailbox name %q", c, name)
+		}
+		if c != '&' {
+			b.WriteByte(c)
+			afterShift = false
+			continue
+		}
+
+		end := strings.IndexByte(name[i+1:], '-')
+		if end < 0 {
+			return "", ProtocolErrorf("unterminated shift sequence in mailbox name %q", name)
+		}
+		end += i + 1
+
+		if end == i+1 {
+			b.WriteByte('&') // "&-"
+			i = end
+			afterShift = false
+			continue
+		}
+		if afterShift {
+			return "", ProtocolErrorf("adjacent shift sequences in mailbox name %q", name)
+		}
+
+		s, err := decodeShift(name[i+1 : end])
+		if err != nil {
+			return "", ProtocolErrorf("%s in mailbox name %q", err, name)
+		}
+		b.WriteString(s)
+		i = end
+		afterShift = true
+	}
+
+	return b.String(), nil
+}
+
+func decodeShift(enc string) (string, error) {
+	buf, err := mutf7.DecodeString(enc)
+	if err != nil || len(buf)%2 != 0 {
+		return "", ProtocolError("invalid base64")
+	}
+
+	// The final base64 character must not carry unused non-zero bits
+	if mutf7.EncodeToString(buf) != enc {
+		return "", ProtocolError("invalid base64")
+	}
+
+	u := make([]uint16, len(buf)/2)
+	for i := range u {
+		u[i] = uint16(buf[2*i])<<8 | uint16(buf[2*i+1])
+	}
+
+	runes := utf16.Decode(u)
+	for _, r := range runes {
+		if r == utf8.RuneError || isDirectChar(r) {
+			return "", ProtocolError("invalid encoded character")
+		}
+	}
+	return string(runes), nil
+}
+
+// ReadMailbox reads a mailbox name and decodes it to UTF-8. Once
+// UTF8=ACCEPT is enabled, names are sent as UTF-8 and aren't decoded.
+func (p *Parser) ReadMailbox() string {
+	name := p.ReadAstring()
+	if !p.Valid() {
+		return ""
+	}
+	return p.decodeMailbox(name)
+}
+
+func (p *Parser) decodeMailbox(name string) string {
+	if p.utf8Accept {
+		return name
+	}
+
+	decoded, err := DecodeMailboxName(name)
+	if err != nil {
+		p.err = err
+		return ""
+	}
+	return decoded
+}
+
+// encodeMailbox prepares a UTF-8 mailbox name for the wire.
+func (c *Conn) encodeMailbox(name string) string {
+	if c.parser.utf8Accept {
+		return name
+	}
+	return EncodeMailboxName(name)
+}
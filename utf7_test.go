@@ -0,0 +1,50 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/paulrosania/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailboxNameEncoding(t *testing.T) {
+	cases := []struct {
+		decoded string
+		encoded string
+	}{
+		{"INBOX", "INBOX"},
+		{"Équipe", "&AMk-quipe"},
+		{"Tom & Jerry", "Tom &- Jerry"},
+		{"~peter/mail/台北/日本語", "~peter/mail/&U,BTFw-/&ZeVnLIqe-"},
+		{"😀", "&2D3eAA-"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.encoded, imap.EncodeMailboxName(c.decoded))
+
+		decoded, err := imap.DecodeMailboxName(c.encoded)
+		assert.NoError(t, err, c.encoded)
+		assert.Equal(t, c.decoded, decoded)
+	}
+}
+
+func TestMailboxNameDecodingRejectsMalformed(t *testing.T) {
+	for _, name := range []string{
+		"&AMk",        // unterminated
+		"&AGE-&AGE-",  // adjacent shifts
+		"&AEE-",       // encoded printable ASCII ("A")
+		"&AM-",        // truncated UTF-16
+		"&2D0-",       // unpaired surrogate
+		"Équipe",      // raw 8-bit
+		"&AMk!-quipe", // invalid base64
+	} {
+		_, err := imap.DecodeMailboxName(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestReadMailboxDecodes(t *testing.T) {
+	p := NewParser(`"&AMk-quipe"`)
+	assert.Equal(t, "Équipe", p.ReadMailbox())
+	assert.NoError(t, p.Err())
+}
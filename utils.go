@@ -2,6 +2,7 @@ package imap
 
 import (
 	"fmt"
+	"mime"
 	"strings"
 
 	"github.com/paulrosania/go-mail"
@@ -11,30 +12,39 @@ func quoteString(s string) string {
 	return fmt.Sprintf("%q", s)
 }
 
-func headerToImapString(h *mail.Header, key string) string {
+// encodeHeaderText RFC 2047-encodes 8-bit text, unless the client accepts
+// UTF-8.
+func encodeHeaderText(s string, utf8 bool) string {
+	if utf8 {
+		return s
+	}
+	return mime.QEncoding.Encode("utf-8", s)
+}
+
+func headerToImapString(h *mail.Header, key string, utf8 bool) string {
 	for _, fld := range h.Fields {
 		if fld.Name() == key {
-			return fmt.Sprintf("%q", fld.Value)
+			return quoteString(encodeHeaderText(fld.Value, utf8))
 		}
 	}
 	return "NIL"
 }
 
-func headerToEnvelopeString(h *mail.Header) string {
+func headerToEnvelopeString(h *mail.Header, utf8 bool) string {
 	return fmt.Sprintf("(%s %s %s %s %s %s %s %s %s %s)",
-		headerToImapString(h, "Date"),
-		headerToImapString(h, "Subject"),
-		addressHeaderToStructureList(h.Get("From")),
-		addressHeaderToStructureList(h.Get("Sender")),
-		addressHeaderToStructureList(h.Get("Reply-To")),
-		addressHeaderToStructureList(h.Get("To")),
-		addressHeaderToStructureList(h.Get("Cc")),
-		addressHeaderToStructureList(h.Get("Bcc")),
-		headerToImapString(h, "In-Reply-To"),
-		headerToImapString(h, "Message-Id"))
+		headerToImapString(h, "Date", utf8),
+		headerToImapString(h, "Subject", utf8),
+		addressHeaderToStructureList(h.Get("From"), utf8),
+		addressHeaderToStructureList(h.Get("Sender"), utf8),
+		addressHeaderToStructureList(h.Get("Reply-To"), utf8),
+		addressHeaderToStructureList(h.Get("To"), utf8),
+		addressHeaderToStructureList(h.Get("Cc"), utf8),
+		addressHeaderToStructureList(h.Get("Bcc"), utf8),
+		headerToImapString(h, "In-Reply-To", utf8),
+		headerToImapString(h, "Message-Id", utf8))
 }
 
-func addressHeaderToStructureList(header string) string {
+func addressHeaderToStructureList(header string, utf8 bool) string {
 	if header == "" {
 		return "NIL"
 	}
@@ -46,7 +56,7 @@ func addressHeaderToStructureList(header string) string {
 		if name == "" {
 			name = "NIL"
 		} else {
-			name = quoteString(name)
+			name = quoteString(encodeHeaderText(name, utf8))
 		}
 		str := fmt.Sprintf("(%s NIL %q %q)", name, a.Localpart, a.Domain)
 		addrs = append(addrs, str)